@@ -0,0 +1,299 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// noCache disables the on-disk cache of parsed entries.
+var noCache bool
+
+// bestEffort keeps the entries a fetch gathered before it failed, such as the
+// first pages of a paginated fetch.
+var bestEffort bool
+
+// staleIfError serves the last cached entries for a source whose fetch fails.
+var staleIfError bool
+
+// showCacheStats prints cache hit and miss counts to stderr after fetching.
+var showCacheStats bool
+
+// printCachePath makes aic print the cache directory and exit.
+var printCachePath bool
+
+var cacheCounters struct {
+	mu     sync.Mutex
+	hits   int
+	misses int
+}
+
+// cacheRecord is the on-disk form of a cached fetch: the hash of the body
+// that was parsed and the entries parsing produced.
+type cacheRecord struct {
+	Key      string           `json:"key"`
+	BodyHash string           `json:"body_hash"`
+	StoredAt time.Time        `json:"stored_at"`
+	Entries  []ChangelogEntry `json:"entries"`
+}
+
+// cacheDir returns the directory holding cached entries: aic in the user
+// cache directory, which is $XDG_CACHE_HOME (or ~/.cache) on Unix,
+// ~/Library/Caches on macOS and %LocalAppData% on Windows.
+func cacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aic"), nil
+}
+
+// cacheKey identifies a cached fetch by URL, the aic version and the options
+// that change how its body is parsed, so an upgrade that parses differently
+// doesn't reuse entries parsed by the old binary.
+func cacheKey(url string) string {
+	return fmt.Sprintf("%s|aic=%s|date-from=%s|commit=%t|raw=%t|backfill=%t|unreleased=%t|keep-tag=%t|tag-prefixes=%s",
+		url, version, releaseDateFrom, includeCommits, includeRaw, backfillDates, includeUnreleased, keepTag, strings.Join(tagPrefixes, ","))
+}
+
+func cacheFile(key string) (string, error) {
+	dir, err := cacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+".json"), nil
+}
+
+// errIncomplete is returned by a parse function passed to cachedParse, along
+// with its entries, when a follow-up lookup such as a release date failed.
+// The entries are still shown, but not cached, so the lookup is retried on
+// the next run instead of its gap being kept until the body changes.
+var errIncomplete = errors.New("follow-up lookup failed")
+
+// cachedParse returns the entries cached for url when body is identical to
+// the body they were parsed from, and otherwise calls parse and caches its
+// result unless it is incomplete. Cache failures never fail the fetch.
+func cachedParse(url string, body []byte, parse func() ([]ChangelogEntry, error)) ([]ChangelogEntry, error) {
+	if noCache {
+		return parseComplete(parse)
+	}
+
+	key := cacheKey(url)
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	path, err := cacheFile(key)
+	if err != nil {
+		return parseComplete(parse)
+	}
+
+	if record, ok := readCacheRecord(path, key); ok && record.BodyHash == hash {
+		countCache(true)
+		return record.Entries, nil
+	}
+	countCache(false)
+
+	entries, err := parse()
+	if errors.Is(err, errIncomplete) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	record := cacheRecord{Key: key, BodyHash: hash, StoredAt: time.Now(), Entries: entries}
+	if data, err := json.Marshal(record); err == nil {
+		if os.MkdirAll(filepath.Dir(path), 0o755) == nil {
+			writeFileAtomic(path, data, 0o644)
+		}
+	}
+
+	return entries, nil
+}
+
+// parseComplete calls parse, accepting incomplete entries.
+func parseComplete(parse func() ([]ChangelogEntry, error)) ([]ChangelogEntry, error) {
+	entries, err := parse()
+	if errors.Is(err, errIncomplete) {
+		return entries, nil
+	}
+	return entries, err
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so concurrent writers and readers never see a partial file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func readCacheRecord(path, key string) (cacheRecord, bool) {
+	var record cacheRecord
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return record, false
+	}
+	if json.Unmarshal(data, &record) != nil || record.Key != key {
+		return record, false
+	}
+	return record, true
+}
+
+// fetchSource calls src.FetchFunc. With -best-effort, entries returned along
+// with an error are used after a warning. With -stale-if-error, a failed
+// fetch falls back to the entries last cached for the source's first URL,
+// whatever their age, and warns how old they are. A redirect loop error is
+// given the source's name.
+func fetchSource(src Source) ([]ChangelogEntry, error) {
+	entries, err := src.FetchFunc()
+	var loop *redirectLoopError
+	if errors.As(err, &loop) {
+		loop.source = src.DisplayName
+	}
+	if err != nil && bestEffort && len(entries) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: Failed to fetch all of %s (%v); showing %d entries fetched before the error\n",
+			src.DisplayName, err, len(entries))
+		return entries, nil
+	}
+	if err == nil || !staleIfError || noCache || src.RequestURLs == nil {
+		return entries, err
+	}
+
+	urls := src.RequestURLs()
+	if len(urls) == 0 {
+		return entries, err
+	}
+	key := cacheKey(urls[0])
+	path, pathErr := cacheFile(key)
+	if pathErr != nil {
+		return entries, err
+	}
+	record, ok := readCacheRecord(path, key)
+	if !ok {
+		return entries, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: Failed to fetch %s (%v); using cached entries from %s ago\n",
+		src.DisplayName, err, formatAge(time.Since(record.StoredAt)))
+	return record.Entries, nil
+}
+
+// formatAge renders d coarsely, in minutes, hours or days.
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+func countCache(hit bool) {
+	cacheCounters.mu.Lock()
+	defer cacheCounters.mu.Unlock()
+	if hit {
+		cacheCounters.hits++
+	} else {
+		cacheCounters.misses++
+	}
+}
+
+// reportCacheStats prints cache hits and misses to stderr when -cache-stats
+// is set.
+func reportCacheStats() {
+	if !showCacheStats {
+		return
+	}
+	cacheCounters.mu.Lock()
+	defer cacheCounters.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "Cache: %d hits, %d misses\n", cacheCounters.hits, cacheCounters.misses)
+}
+
+// runCacheCommand implements "aic cache clear" and "aic cache info" and
+// returns the exit code.
+func runCacheCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: cache requires a subcommand (clear, info)\n")
+		return 1
+	}
+
+	dir, err := cacheDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	switch args[0] {
+	case "clear":
+		removed := 0
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				return 1
+			}
+			removed++
+		}
+		if !quiet {
+			fmt.Printf("Removed %d cache entries from %s\n", removed, dir)
+		}
+	case "info":
+		var size int64
+		for _, file := range files {
+			if info, err := os.Stat(file); err == nil {
+				size += info.Size()
+			}
+		}
+		fmt.Printf("Path:    %s\n", dir)
+		fmt.Printf("Entries: %d\n", len(files))
+		fmt.Printf("Size:    %s\n", formatBytes(size))
+	default:
+		fmt.Fprintf(os.Stderr, "Error: Unknown cache subcommand '%s' (expected clear or info)\n", args[0])
+		return 1
+	}
+	return 0
+}
+
+// formatBytes renders n bytes in a human-readable unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestFetchClaudeChangelogRetriesFailedDateLookup(t *testing.T) {
+	useTempCache(t)
+	commitsUp := false
+	commitCalls := 0
+	newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/anthropics/claude-code/raw/main/CHANGELOG.md":
+			fmt.Fprint(w, "# Changelog\n\n## 1.0.1\n\n- Fix\n\n## 1.0.0\n\n- Launch\n")
+		case "/api/v3/repos/anthropics/claude-code/commits":
+			commitCalls++
+			if !commitsUp {
+				http.Error(w, `{"message": "API rate limit exceeded"}`, http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `[{"commit": {"message": "1.0.1", "committer": {"date": "2024-05-01T10:00:00Z"}}}]`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	entries, err := fetchClaudeChangelog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entries[0].ReleasedAt.IsZero() {
+		t.Fatalf("ReleasedAt = %v with the lookup failing, want zero", entries[0].ReleasedAt)
+	}
+
+	// The failed lookup must not be cached, so it is retried.
+	commitsUp = true
+	entries, err = fetchClaudeChangelog()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := entries[0].ReleasedAt.Format("2006-01-02"); got != "2024-05-01" {
+		t.Fatalf("ReleasedAt = %s after the lookup recovered, want 2024-05-01", got)
+	}
+
+	// The complete result is cached, so the lookup is not repeated.
+	if _, err := fetchClaudeChangelog(); err != nil {
+		t.Fatal(err)
+	}
+	if commitCalls != 2 {
+		t.Errorf("commits API called %d times, want 2", commitCalls)
+	}
+}
+
+func TestCachedParseConcurrentWriters(t *testing.T) {
+	useTempCache(t)
+	const url = "https://example.com/CHANGELOG.md"
+	path, err := cacheFile(cacheKey(url))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Writers store differing bodies under the same key while readers load
+	// it; every record read must be whole.
+	var wg sync.WaitGroup
+	errs := make(chan error, 64)
+	for i := range 16 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			body := []byte(fmt.Sprintf("body %d", i))
+			entries, err := cachedParse(url, body, func() ([]ChangelogEntry, error) {
+				return []ChangelogEntry{{Version: fmt.Sprintf("1.%d.0", i), Changes: []string{string(body)}}}, nil
+			})
+			if err != nil || len(entries) != 1 {
+				errs <- fmt.Errorf("cachedParse = %v, %v", entries, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					continue
+				}
+				var record cacheRecord
+				if err := json.Unmarshal(data, &record); err != nil {
+					errs <- fmt.Errorf("read a partial cache file: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	record, ok := readCacheRecord(path, cacheKey(url))
+	if !ok || len(record.Entries) != 1 {
+		t.Fatalf("cache entry after concurrent writes = %+v, %v; want one valid entry", record, ok)
+	}
+	sum := sha256.Sum256([]byte(record.Entries[0].Changes[0]))
+	if record.BodyHash != hex.EncodeToString(sum[:]) {
+		t.Errorf("cache entry mixes writers: hash doesn't match its entries")
+	}
+
+	// Only the atomic rename leaves files behind.
+	files, _ := os.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("cache dir holds %d files, want 1", len(files))
+	}
+}
+
+func TestCachedParseKeyedByVersion(t *testing.T) {
+	useTempCache(t)
+	old := version
+	t.Cleanup(func() { version = old })
+
+	body := []byte("## 1.0.0\n")
+	tests := []struct {
+		version string
+		parsed  bool
+	}{
+		{"1.0.0", true},
+		{"1.0.0", false},
+		{"1.1.0", true},
+		{"1.1.0", false},
+	}
+	for i, tt := range tests {
+		version = tt.version
+		parsed := false
+		_, err := cachedParse("https://example.com/CHANGELOG.md", body, func() ([]ChangelogEntry, error) {
+			parsed = true
+			return []ChangelogEntry{{Version: "1.0.0"}}, nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != tt.parsed {
+			t.Errorf("run %d with aic %s: parsed = %v, want %v", i+1, tt.version, parsed, tt.parsed)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// parseSinceFlag parses a -since value: a date (YYYY-MM-DD), a number of days
+// or weeks ("7d", "2w"), or a Go duration ("36h"). It returns the cutoff
+// time, exiting on anything else.
+func parseSinceFlag(value string) time.Time {
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t
+	}
+	if age, ok := parseAge(value); ok {
+		return time.Now().Add(-age)
+	}
+	fatalf("-since expects a date (YYYY-MM-DD) or age (7d, 2w, 36h), got '%s'", value)
+	return time.Time{}
+}
+
+// parseAge parses a non-negative age given as a number of days or weeks
+// ("7d", "2w") or as a Go duration ("36h").
+func parseAge(value string) (time.Duration, bool) {
+	for suffix, days := range map[string]int{"d": 1, "w": 7} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(value, suffix)); err == nil && strings.HasSuffix(value, suffix) && n >= 0 {
+			return time.Duration(n*days) * 24 * time.Hour, true
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return d, true
+	}
+	return 0, false
+}
+
+// sortedSources returns the sources selected by -sources and
+// -exclude-sources, which may name aliases, sorted by name.
+func sortedSources(cfg *Config, include, exclude []string) []Source {
+	for i, name := range include {
+		include[i] = cfg.resolveAlias(name)
+	}
+	for i, name := range exclude {
+		exclude[i] = cfg.resolveAlias(name)
+	}
+
+	selected, err := selectSources(include, exclude)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	var srcs []Source
+	for _, src := range selected {
+		srcs = append(srcs, src)
+	}
+	sort.Slice(srcs, func(i, j int) bool {
+		return srcs[i].Name < srcs[j].Name
+	})
+	return srcs
+}
+
+// fetchReleasedSince fetches srcs concurrently and returns, for each, its
+// entries released at or after cutoff with Source set, or the error fetching
+// it.
+// Failures are also reported as warnings.
+func fetchReleasedSince(srcs []Source, cutoff time.Time) ([][]ChangelogEntry, []error) {
+	prog := newProgress(len(srcs))
+	prog.start("Fetching")
+
+	released := make([][]ChangelogEntry, len(srcs))
+	failed := make([]error, len(srcs))
+	var wg sync.WaitGroup
+	for i, src := range srcs {
+		wg.Add(1)
+		go func(i int, src Source) {
+			defer wg.Done()
+			entries, err := fetchSource(src)
+			released[i] = entriesBetween(entries, cutoff, time.Time{})
+			for j := range released[i] {
+				released[i][j].Source = src.DisplayName
+			}
+			failed[i] = err
+			prog.step(src.DisplayName)
+		}(i, src)
+	}
+	wg.Wait()
+	prog.clear()
+	reportCacheStats()
+
+	for i, err := range failed {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch %s: %v\n", srcs[i].DisplayName, err)
+		}
+	}
+	return released, failed
+}
+
+// runCompareCommand implements "aic compare": the releases of every source
+// since a cutoff, grouped by source. It returns the exit code.
+func runCompareCommand(args []string, cfg *Config) int {
+	cutoff := time.Now().AddDate(0, 0, -7)
+	var jsonOutput bool
+	var include, exclude []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-json", "--json":
+			jsonOutput = true
+		case "-since", "--since":
+			if i+1 < len(args) {
+				cutoff = parseSinceFlag(args[i+1])
+				i++
+			}
+		case "-sources", "--sources":
+			if i+1 < len(args) {
+				include = splitList(args[i+1])
+				i++
+			}
+		case "-exclude-sources", "--exclude-sources":
+			if i+1 < len(args) {
+				exclude = splitList(args[i+1])
+				i++
+			}
+		}
+	}
+	srcs := sortedSources(cfg, include, exclude)
+	released, failed := fetchReleasedSince(srcs, cutoff)
+
+	if jsonOutput {
+		keyed := map[string][]ChangelogEntry{}
+		for i, src := range srcs {
+			if failed[i] != nil {
+				continue
+			}
+			if released[i] == nil {
+				released[i] = []ChangelogEntry{}
+			}
+			keyed[src.Name] = released[i]
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(keyed)
+		return 0
+	}
+
+	fmt.Printf("Releases since %s\n", cutoff.Format("2006-01-02"))
+	for i, src := range srcs {
+		if failed[i] != nil {
+			continue
+		}
+		fmt.Println()
+		switch len(released[i]) {
+		case 0:
+			fmt.Printf("%s: no releases\n", src.DisplayName)
+			continue
+		case 1:
+			fmt.Printf("%s (1 release)\n", src.DisplayName)
+		default:
+			fmt.Printf("%s (%d releases)\n", src.DisplayName, len(released[i]))
+		}
+		for _, entry := range released[i] {
+			changes := fmt.Sprintf("%d changes", countChanges(&entry))
+			if countChanges(&entry) == 1 {
+				changes = "1 change"
+			}
+			fmt.Printf("  %-20s %s  %s\n", entry.Version, entry.ReleasedAt.Format("2006-01-02"), changes)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+type completionItem struct {
+	Name        string
+	Description string
+}
+
+var completionCommands = []completionItem{
+	{"latest", "Show releases from all sources in last 24h"},
+	{"all", "Show the newest entry of every source"},
+	{"compare", "List releases of every source in a window"},
+	{"digest", "Markdown digest of releases this week or month"},
+	{"watch", "Poll all sources and report new releases"},
+	{"diff", "Show changes added and removed between two versions"},
+	{"list-sources", "List available sources"},
+	{"file", "Read a changelog from a file or URL"},
+	{"gist", "Read a changelog from a GitHub Gist"},
+	{"cache", "Remove or describe cached entries"},
+	{"tui", "Browse sources and versions interactively"},
+	{"serve", "Serve changelogs as JSON over HTTP"},
+	{"completions", "Print a shell completion script"},
+	{"help", "Show help"},
+}
+
+var completionFlags = []completionItem{
+	{"json", "Output as JSON"},
+	{"md", "Output as markdown"},
+	{"shell", "Print each change on its own line, shell-quoted"},
+	{"template", "Format each entry with a Go template"},
+	{"template-file", "Format each entry with a Go template from a file"},
+	{"front-matter", "Prepend YAML front-matter to -md output"},
+	{"md-no-date", "Leave the date out of the -md heading"},
+	{"md-no-sections", "Print -md changes without section headings"},
+	{"strip-pr-links", "Drop bare pull request URLs from -md changes"},
+	{"output-dir", "With -md, write each source to its own file"},
+	{"list", "List all versions"},
+	{"all", "Show every entry"},
+	{"n", "Show the n newest entries in full"},
+	{"toc", "Show only section names with change counts"},
+	{"category-counts", "Print change counts per section as JSON"},
+	{"head", "Show at most n changes per entry"},
+	{"wrap", "Wrap plain text at a column width"},
+	{"no-emoji", "Strip leading emoji from plain text"},
+	{"no-separator", "Leave out the dashed line under plain text headers"},
+	{"separator-width", "Length of the dashed line under plain text headers"},
+	{"prefix", "Prefix plain text lines with the source name, e.g. [claude]"},
+	{"theme", "Color theme: dark, light, mono"},
+	{"max-change-len", "Truncate changes to n characters"},
+	{"no-ungrouped", "Hide changes outside any section"},
+	{"only-ungrouped", "Show only changes outside any section"},
+	{"normalize-sections", "Rename sections to canonical categories"},
+	{"no-merge-sections", "Keep repeated sections separate"},
+	{"sort-changes", "Sort changes alphabetically within sections"},
+	{"flatten-single-section", "Drop the header when a release has one section"},
+	{"flat", "List all changes without section headers"},
+	{"annotate-version", "Prefix each change with its version"},
+	{"json-minify-changes", "Strip markdown from change text in JSON"},
+	{"uniform-sections", "In JSON, move ungrouped changes into an Other section"},
+	{"version", "Get specific version"},
+	{"version-latest", "Print only the newest version"},
+	{"as-of", "Only consider entries released on or before a date"},
+	{"current", "Check an installed version against the latest"},
+	{"since-version", "Show every release newer than a version"},
+	{"if-changed", "Print nothing unless the newest version changed"},
+	{"github-host", "Use a GitHub Enterprise host"},
+	{"user-agent", "User-Agent for all requests"},
+	{"quiet", "Suppress progress output"},
+	{"no-cache", "Don't use the parsed-entry cache"},
+	{"cache-stats", "Print cache hits and misses"},
+	{"print-cache-path", "Print the cache directory and exit"},
+	{"stale-if-error", "Use the last cached entries when a fetch fails"},
+	{"max-pages", "Pages of GitHub releases to fetch"},
+	{"best-effort", "Use pages fetched before a failure"},
+	{"dry-run", "Print the URLs and parser a fetch would use"},
+	{"open", "Open the release page in a browser"},
+	{"date-from", "Release date source: published, name"},
+	{"commit", "Include release commit SHAs"},
+	{"include-raw", "Include unparsed release bodies"},
+	{"backfill-dates", "Date markdown entries from their tags"},
+	{"unreleased", "Include the Unreleased section of a changelog"},
+	{"keep-tag", "Use release tags as versions unchanged"},
+	{"summary", "Print totals after latest output"},
+	{"jsonl-file", "Append new latest entries to an NDJSON file"},
+	{"dedupe-across", "Collapse changes repeated across latest entries"},
+	{"strict", "Exit non-zero if any latest source fails"},
+	{"sources", "Only fetch these sources in latest or all"},
+	{"exclude-sources", "Skip these sources in latest or all"},
+	{"parser", "Parser for file: keepachangelog, github, markdown"},
+	{"pattern", "Version heading regex for file"},
+	{"file", "Gist file to read"},
+	{"addr", "Listen address for serve"},
+	{"since", "Start of the release window: date or age (7d)"},
+	{"weekly", "Digest the current ISO week"},
+	{"monthly", "Digest the current month"},
+	{"interval", "How often watch polls (default 10m)"},
+	{"json-stream", "Print each release watch finds as a JSON line"},
+	{"until", "End of the release window: date"},
+	{"max-age", "Warn about sources with no release within an age"},
+	{"stat", "Print only diff counts"},
+}
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// writeCompletions writes a completion script for shell. Source names are
+// taken from the sources map so new sources are picked up automatically.
+func writeCompletions(w io.Writer, shell string) error {
+	switch shell {
+	case "bash":
+		writeBashCompletions(w)
+	case "zsh":
+		writeZshCompletions(w)
+	case "fish":
+		writeFishCompletions(w)
+	default:
+		return fmt.Errorf("unsupported shell '%s' (expected %s)", shell, strings.Join(completionShells, ", "))
+	}
+	return nil
+}
+
+func completionSources() []completionItem {
+	var items []completionItem
+	for name, src := range sources {
+		items = append(items, completionItem{name, src.DisplayName})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Name < items[j].Name
+	})
+	return items
+}
+
+func completionWords(items []completionItem, prefix string) string {
+	var words []string
+	for _, item := range items {
+		words = append(words, prefix+item.Name)
+	}
+	return strings.Join(words, " ")
+}
+
+func writeBashCompletions(w io.Writer) {
+	first := completionWords(append(completionSources(), completionCommands...), "")
+	fmt.Fprintf(w, `_aic() {
+    local cur prev
+    cur="${COMP_WORDS[COMP_CWORD]}"
+    prev="${COMP_WORDS[COMP_CWORD-1]}"
+
+    if [ "$COMP_CWORD" -eq 1 ]; then
+        COMPREPLY=( $(compgen -W "%s" -- "$cur") )
+        return
+    fi
+
+    if [ "$prev" = "completions" ]; then
+        COMPREPLY=( $(compgen -W "%s" -- "$cur") )
+        return
+    fi
+
+    COMPREPLY=( $(compgen -W "%s" -- "$cur") )
+}
+complete -F _aic aic
+`, first, strings.Join(completionShells, " "), completionWords(completionFlags, "-"))
+}
+
+func writeZshCompletions(w io.Writer) {
+	first := completionWords(append(completionSources(), completionCommands...), "")
+	fmt.Fprintf(w, `#compdef aic
+
+_aic() {
+    if (( CURRENT == 2 )); then
+        compadd -- %s
+    elif [[ ${words[2]} == completions ]]; then
+        compadd -- %s
+    else
+        compadd -- %s
+    fi
+}
+
+compdef _aic aic
+`, first, strings.Join(completionShells, " "), completionWords(completionFlags, "-"))
+}
+
+func writeFishCompletions(w io.Writer) {
+	fmt.Fprintln(w, "complete -c aic -f")
+	for _, item := range append(completionSources(), completionCommands...) {
+		fmt.Fprintf(w, "complete -c aic -n __fish_use_subcommand -a %s -d '%s'\n", item.Name, item.Description)
+	}
+	fmt.Fprintf(w, "complete -c aic -n '__fish_seen_subcommand_from completions' -a '%s'\n", strings.Join(completionShells, " "))
+	for _, item := range completionFlags {
+		fmt.Fprintf(w, "complete -c aic -n 'not __fish_use_subcommand' -o %s -d '%s'\n", item.Name, item.Description)
+	}
+}
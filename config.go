@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Config holds user settings read from the config file.
+type Config struct {
+	Aliases map[string]string
+
+	// LatestSources limits which sources the latest command fetches.
+	LatestSources []string
+
+	// DefaultSource is shown when no source is named on the command line.
+	DefaultSource string
+
+	// TagPrefixes, when set, replaces the prefixes removed from GitHub
+	// release tags. An empty list keeps tags unchanged.
+	TagPrefixes []string
+
+	// GitHubRate is the most GitHub API requests sent per second; 0 means
+	// no limit.
+	GitHubRate int
+
+	// CustomSources are the sources defined by [sources.<name>] sections,
+	// sorted by name.
+	CustomSources []Source
+}
+
+// configPath returns the location of the config file. AIC_CONFIG overrides the
+// default of <user config dir>/aic/config.toml.
+func configPath() (string, error) {
+	if p := os.Getenv("AIC_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "aic", "config.toml"), nil
+}
+
+// loadConfig reads the config file. A missing file yields an empty config.
+func loadConfig() (*Config, error) {
+	cfg := &Config{Aliases: map[string]string{}, GitHubRate: defaultGitHubRate}
+
+	path, err := configPath()
+	if err != nil {
+		return cfg, nil
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sections, err := parseConfig(f.Name(), bufio.NewScanner(f))
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range sections["aliases"] {
+		cfg.Aliases[key] = value
+	}
+	cfg.DefaultSource = sections[""]["default_source"]
+	if value, ok := sections["latest"]["sources"]; ok {
+		cfg.LatestSources = splitList(value)
+	}
+	if value, ok := sections["releases"]["strip_prefixes"]; ok {
+		cfg.TagPrefixes = append([]string{}, splitList(value)...)
+	}
+	if value, ok := sections["github"]["requests_per_second"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s: [github]: requests_per_second expects a number, got '%s'", path, value)
+		}
+		cfg.GitHubRate = n
+	}
+
+	for section, values := range sections {
+		name, ok := strings.CutPrefix(section, "sources.")
+		if !ok {
+			continue
+		}
+		src, err := customSource(name, values)
+		if err != nil {
+			return nil, fmt.Errorf("%s: [%s]: %w", path, section, err)
+		}
+		cfg.CustomSources = append(cfg.CustomSources, src)
+	}
+	sort.Slice(cfg.CustomSources, func(i, j int) bool {
+		return cfg.CustomSources[i].Name < cfg.CustomSources[j].Name
+	})
+
+	return cfg, nil
+}
+
+// customSource builds the source a [sources.<name>] section describes: a
+// gitlab-releases source needs a project path, and a markdown-raw source the
+// urls of its changelogs, optionally with a parser or pattern as for "aic
+// file".
+func customSource(name string, values map[string]string) (Source, error) {
+	if name == "" {
+		return Source{}, fmt.Errorf("missing source name")
+	}
+	displayName := values["display_name"]
+	if displayName == "" {
+		displayName = name
+	}
+
+	switch kind := values["kind"]; kind {
+	case "gitlab-releases":
+		project := values["project"]
+		if project == "" {
+			return Source{}, fmt.Errorf("gitlab-releases needs a project, such as \"group/project\"")
+		}
+		return gitlabSource(name, displayName, project), nil
+	case "markdown-raw":
+		urls := splitList(values["urls"])
+		if len(urls) == 0 {
+			return Source{}, fmt.Errorf("markdown-raw needs urls, such as \"https://example.com/CHANGELOG.md\"")
+		}
+		return markdownSource(name, displayName, urls, values["parser"], values["pattern"]), nil
+	case "":
+		return Source{}, fmt.Errorf("missing kind (expected gitlab-releases or markdown-raw)")
+	default:
+		return Source{}, fmt.Errorf("unknown kind %q (expected gitlab-releases or markdown-raw)", kind)
+	}
+}
+
+// parseConfig parses the small TOML subset used by the config file: [section]
+// headers, key = "value" pairs, and # comments.
+func parseConfig(name string, scanner *bufio.Scanner) (map[string]map[string]string, error) {
+	sections := map[string]map[string]string{"": {}}
+	current := ""
+
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			if sections[current] == nil {
+				sections[current] = map[string]string{}
+			}
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", name, lineNum)
+		}
+		key = strings.TrimSpace(key)
+		value = strings.Trim(strings.TrimSpace(value), `"`)
+		if key == "" {
+			return nil, fmt.Errorf("%s:%d: missing key", name, lineNum)
+		}
+		sections[current][key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// resolveAlias maps a configured alias to its canonical source name.
+func (c *Config) resolveAlias(name string) string {
+	if target, ok := c.Aliases[name]; ok {
+		return target
+	}
+	return name
+}
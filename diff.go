@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// changeDiff holds the changes added and removed between two entries.
+type changeDiff struct {
+	Added   []string
+	Removed []string
+}
+
+// diffEntries compares the changes of two entries as sets, ignoring their
+// sections. Changes keep the order they have in their entry and are listed
+// once even when an entry repeats them, so -stat counts distinct changes.
+func diffEntries(from, to ChangelogEntry) changeDiff {
+	before := flattenSections(from).Changes
+	after := flattenSections(to).Changes
+
+	inBefore := map[string]bool{}
+	for _, change := range before {
+		inBefore[change] = true
+	}
+	inAfter := map[string]bool{}
+	for _, change := range after {
+		inAfter[change] = true
+	}
+
+	// Marking a listed change as present on the other side skips its repeats.
+	var diff changeDiff
+	for _, change := range after {
+		if !inBefore[change] {
+			diff.Added = append(diff.Added, change)
+			inBefore[change] = true
+		}
+	}
+	for _, change := range before {
+		if !inAfter[change] {
+			diff.Removed = append(diff.Removed, change)
+			inAfter[change] = true
+		}
+	}
+	return diff
+}
+
+// previousEntry returns the entry with the highest version below ver, or nil
+// when ver is the oldest.
+func previousEntry(entries []ChangelogEntry, ver string) *ChangelogEntry {
+	var prev *ChangelogEntry
+	for i := range entries {
+		if compareVersions(entries[i].Version, ver) < 0 &&
+			(prev == nil || compareVersions(entries[i].Version, prev.Version) > 0) {
+			prev = &entries[i]
+		}
+	}
+	return prev
+}
+
+// runDiffCommand implements "aic diff <source> [<from>] <to>" and returns the
+// exit code. Without <from>, <to> is compared with the version before it.
+func runDiffCommand(args []string, cfg *Config) int {
+	var positional []string
+	var stat, jsonOutput bool
+	opts := outputOptions{separatorWidth: defaultSeparatorWidth()}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "-stat", "--stat":
+			stat = true
+		case "-json", "--json":
+			jsonOutput = true
+		case "-no-separator", "--no-separator":
+			opts.noSeparator = true
+		case "-separator-width", "--separator-width":
+			if i+1 < len(args) {
+				opts.separatorWidth = parsePositiveFlag("-separator-width", args[i+1])
+				i++
+			}
+		default:
+			if strings.HasPrefix(arg, "-") {
+				fatalf("Unknown diff flag '%s'", arg)
+			}
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) != 2 && len(positional) != 3 {
+		fatalf("diff requires a source and one or two versions: aic diff <source> [<from>] <to>")
+	}
+
+	name := cfg.resolveAlias(positional[0])
+	src, ok := sources[name]
+	if !ok {
+		fatalf("Unknown source '%s'", name)
+	}
+
+	entries, err := fetchSource(src)
+	reportCacheStats()
+	if err != nil {
+		fatalf("failed to fetch changelog: %v", err)
+	}
+
+	to := selectEntry(entries, positional[len(positional)-1])
+	if to == nil {
+		fatalf("Version %s not found", positional[len(positional)-1])
+	}
+	var from *ChangelogEntry
+	if len(positional) == 3 {
+		from = selectEntry(entries, positional[1])
+		if from == nil {
+			fatalf("Version %s not found", positional[1])
+		}
+	} else {
+		from = previousEntry(entries, to.Version)
+		if from == nil {
+			fatalf("%s is the oldest version; there is nothing to compare it against", to.Version)
+		}
+	}
+
+	diff := diffEntries(*from, *to)
+
+	if stat {
+		if jsonOutput {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			encoder.Encode(map[string]int{"added": len(diff.Added), "removed": len(diff.Removed)})
+			return 0
+		}
+		fmt.Printf("+%d changes, -%d changes\n", len(diff.Added), len(diff.Removed))
+		return 0
+	}
+
+	if jsonOutput {
+		// Empty lists are written as [] rather than null.
+		out := struct {
+			From    string   `json:"from"`
+			To      string   `json:"to"`
+			Added   []string `json:"added"`
+			Removed []string `json:"removed"`
+		}{from.Version, to.Version, []string{}, []string{}}
+		out.Added = append(out.Added, diff.Added...)
+		out.Removed = append(out.Removed, diff.Removed...)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(out)
+		return 0
+	}
+
+	fmt.Printf("%s %s → %s\n", src.DisplayName, from.Version, to.Version)
+	writeSeparator(os.Stdout, opts)
+	for _, change := range diff.Added {
+		fmt.Printf("+ %s\n", change)
+	}
+	for _, change := range diff.Removed {
+		fmt.Printf("- %s\n", change)
+	}
+	return 0
+}
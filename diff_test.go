@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffEntries(t *testing.T) {
+	from := ChangelogEntry{Sections: []Section{{Name: "Fixed", Changes: []string{"A", "B"}}}, Changes: []string{"C"}}
+	to := ChangelogEntry{Sections: []Section{{Name: "Changed", Changes: []string{"B", "D"}}}, Changes: []string{"E", "A"}}
+	got := diffEntries(from, to)
+	want := changeDiff{Added: []string{"D", "E"}, Removed: []string{"C"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffEntries = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffEntriesRepeatedChanges(t *testing.T) {
+	from := ChangelogEntry{Sections: []Section{{Name: "Fixed", Changes: []string{"A", "B"}}, {Name: "Other", Changes: []string{"B"}}}}
+	to := ChangelogEntry{Sections: []Section{{Name: "Added", Changes: []string{"C"}}, {Name: "Fixed", Changes: []string{"C", "A"}}}}
+	got := diffEntries(from, to)
+	want := changeDiff{Added: []string{"C"}, Removed: []string{"B"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("diffEntries = %+v, want %+v", got, want)
+	}
+}
+
+func TestPreviousEntry(t *testing.T) {
+	entries := []ChangelogEntry{{Version: "1.10.0"}, {Version: "1.2.0"}, {Version: "1.9.0"}}
+	tests := []struct {
+		version, want string
+	}{
+		{"1.10.0", "1.9.0"},
+		{"1.9.0", "1.2.0"},
+		{"1.2.0", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if prev := previousEntry(entries, tt.version); prev != nil {
+			got = prev.Version
+		}
+		if got != tt.want {
+			t.Errorf("previousEntry(%s) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestRunDiffCommand(t *testing.T) {
+	sources["tool"] = testSource("tool",
+		ChangelogEntry{Version: "1.3.0", Sections: []Section{{Name: "Added", Changes: []string{"Flag"}}}, Changes: []string{"Kept"}},
+		ChangelogEntry{Version: "1.2.0", Changes: []string{"Kept", "Dropped"}},
+		ChangelogEntry{Version: "1.1.0", Changes: []string{"Old"}},
+	)
+	t.Cleanup(func() { delete(sources, "tool") })
+	cfg := &Config{Aliases: map[string]string{}}
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "json",
+			args: []string{"tool", "1.2.0", "1.3.0", "-json"},
+			want: "{\n  \"from\": \"1.2.0\",\n  \"to\": \"1.3.0\",\n  \"added\": [\n    \"Flag\"\n  ],\n  \"removed\": [\n    \"Dropped\"\n  ]\n}\n",
+		},
+		{
+			name: "json against the previous version",
+			args: []string{"tool", "1.2.0", "-json"},
+			want: "{\n  \"from\": \"1.1.0\",\n  \"to\": \"1.2.0\",\n  \"added\": [\n    \"Kept\",\n    \"Dropped\"\n  ],\n  \"removed\": [\n    \"Old\"\n  ]\n}\n",
+		},
+		{
+			name: "json with no changes",
+			args: []string{"tool", "1.3.0", "1.3.0", "-json"},
+			want: "{\n  \"from\": \"1.3.0\",\n  \"to\": \"1.3.0\",\n  \"added\": [],\n  \"removed\": []\n}\n",
+		},
+		{
+			name: "json stat",
+			args: []string{"tool", "1.3.0", "-stat", "-json"},
+			want: "{\n  \"added\": 1,\n  \"removed\": 1\n}\n",
+		},
+		{
+			name: "plain",
+			args: []string{"tool", "1.3.0", "-separator-width", "10"},
+			want: "TOOL 1.2.0 → 1.3.0\n----------\n+ Flag\n- Dropped\n",
+		},
+		{
+			name: "plain without separator",
+			args: []string{"tool", "1.3.0", "-no-separator"},
+			want: "TOOL 1.2.0 → 1.3.0\n+ Flag\n- Dropped\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var code int
+			got := captureStdout(t, func() { code = runDiffCommand(tt.args, cfg) })
+			if code != 0 {
+				t.Fatalf("exit %d", code)
+			}
+			if got != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// digestPeriod returns the start of the ISO week, or with monthly the month,
+// containing now, and a title naming it.
+func digestPeriod(now time.Time, monthly bool) (time.Time, string) {
+	year, month, day := now.Date()
+	if monthly {
+		start := time.Date(year, month, 1, 0, 0, 0, 0, now.Location())
+		return start, start.Format("January 2006")
+	}
+	// Weekday counts from Sunday; ISO weeks start on Monday.
+	offset := (int(now.Weekday()) + 6) % 7
+	start := time.Date(year, month, day-offset, 0, 0, 0, 0, now.Location())
+	isoYear, week := now.ISOWeek()
+	return start, fmt.Sprintf("Week %d-W%02d", isoYear, week)
+}
+
+// runDigestCommand implements "aic digest": a markdown digest of every
+// source's releases in the current week or month, grouped by source. It
+// returns the exit code.
+func runDigestCommand(args []string, cfg *Config) int {
+	var monthly, annotate bool
+	var since time.Time
+	var include, exclude []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-weekly", "--weekly":
+			monthly = false
+		case "-monthly", "--monthly":
+			monthly = true
+		case "-annotate-version", "--annotate-version":
+			annotate = true
+		case "-since", "--since":
+			if i+1 < len(args) {
+				since = parseSinceFlag(args[i+1])
+				i++
+			}
+		case "-sources", "--sources":
+			if i+1 < len(args) {
+				include = splitList(args[i+1])
+				i++
+			}
+		case "-exclude-sources", "--exclude-sources":
+			if i+1 < len(args) {
+				exclude = splitList(args[i+1])
+				i++
+			}
+		}
+	}
+
+	start, title := digestPeriod(time.Now(), monthly)
+	if !since.IsZero() {
+		start, title = since, "Since "+since.Format("2006-01-02")
+	}
+
+	srcs := sortedSources(cfg, include, exclude)
+	released, failed := fetchReleasedSince(srcs, start)
+
+	releases, sourceCount := 0, 0
+	var idle []string
+	for i, src := range srcs {
+		if failed[i] != nil {
+			continue
+		}
+		if len(released[i]) == 0 {
+			idle = append(idle, src.DisplayName)
+			continue
+		}
+		releases += len(released[i])
+		sourceCount++
+	}
+
+	fmt.Printf("# %s\n\n", title)
+	switch releases {
+	case 0:
+		fmt.Printf("No releases since %s.\n", start.Format("2006-01-02"))
+	case 1:
+		fmt.Printf("1 release since %s.\n", start.Format("2006-01-02"))
+	default:
+		fmt.Printf("%d releases from %d sources since %s.\n", releases, sourceCount, start.Format("2006-01-02"))
+	}
+
+	for i, src := range srcs {
+		if failed[i] != nil || len(released[i]) == 0 {
+			continue
+		}
+
+		changes := 0
+		for _, entry := range released[i] {
+			changes += countChanges(&entry)
+		}
+		fmt.Printf("\n## %s (%s, %s)\n", src.DisplayName,
+			plural(len(released[i]), "release"), plural(changes, "change"))
+
+		for _, entry := range released[i] {
+			fmt.Printf("\n### %s (%s)\n\n", entry.Version, entry.ReleasedAt.Format("2006-01-02"))
+			if annotate {
+				entry = annotateVersion(entry)
+			}
+			for _, change := range flattenSections(entry).Changes {
+				fmt.Printf("- %s\n", markdownItem(change))
+			}
+		}
+	}
+
+	if len(idle) > 0 && releases > 0 {
+		fmt.Printf("\nNo releases from %s.\n", strings.Join(idle, ", "))
+	}
+	return 0
+}
+
+// plural formats n with noun, adding an s unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
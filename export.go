@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// safeFileName turns a source name into a file name stem, replacing anything
+// but lowercase letters, digits, dots, dashes and underscores with dashes.
+func safeFileName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '-'
+	}, strings.ToLower(name))
+	name = strings.Trim(name, ".-")
+	if name == "" {
+		name = "source"
+	}
+	return name
+}
+
+// runOutputDir writes each source's selected entry, or with allEntries every
+// entry (the newest limit of them when limit is set), as markdown to
+// <dir>/<source>.md, creating dir if needed. Sources that fail are reported
+// as warnings. It returns the exit code, which is 1 only if no file was
+// written.
+func runOutputDir(srcs []Source, dir, targetVersion string, allEntries bool, limit int, opts outputOptions) int {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fatalf("%v", err)
+	}
+
+	results := fetchEach(srcs)
+	reportCacheStats()
+
+	written := 0
+	for i, src := range srcs {
+		r := results[i]
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch %s: %v\n", src.DisplayName, r.err)
+			continue
+		}
+		if len(r.entries) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: No changelog entries found for %s\n", src.DisplayName)
+			continue
+		}
+
+		entries := r.entries
+		if limit > 0 && len(entries) > limit {
+			entries = entries[:limit]
+		}
+		if !allEntries {
+			entry := selectEntry(entries, targetVersion)
+			if entry == nil {
+				fmt.Fprintf(os.Stderr, "Warning: Version %s not found for %s\n", targetVersion, src.DisplayName)
+				continue
+			}
+			entries = []ChangelogEntry{*entry}
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "# %s\n\n", src.DisplayName)
+		for j, entry := range entries {
+			if j > 0 {
+				fmt.Fprintln(&buf)
+			}
+			shown := applyOutputOptions(entry, opts)
+			outputMarkdown(&buf, src.DisplayName, &shown, opts)
+		}
+
+		path := filepath.Join(dir, safeFileName(src.Name)+".md")
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to write %s: %v\n", path, err)
+			continue
+		}
+		if !quiet {
+			fmt.Fprintf(os.Stderr, "Wrote %s\n", path)
+		}
+		written++
+	}
+
+	if written == 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// gitlabAPI is the REST API root that GitLab sources are fetched from.
+var gitlabAPI = "https://gitlab.com/api/v4"
+
+// gitlabReleasesPageURL returns the URL of one page of a project's releases.
+// projectPath ("group/project") is encoded into a single path segment, as
+// the API expects. The first page has no page parameter.
+func gitlabReleasesPageURL(projectPath string, page int) string {
+	u := fmt.Sprintf("%s/projects/%s/releases", gitlabAPI, url.PathEscape(projectPath))
+	if page > 1 {
+		u += fmt.Sprintf("?page=%d", page)
+	}
+	return u
+}
+
+// gitlabReleaseURLs lists the URLs fetchGitLabReleases requests for a
+// project.
+func gitlabReleaseURLs(projectPath string) func() []string {
+	return func() []string {
+		var urls []string
+		for page := 1; page <= maxPages; page++ {
+			urls = append(urls, gitlabReleasesPageURL(projectPath, page))
+		}
+		return urls
+	}
+}
+
+// gitlabSource returns a source for the releases of a gitlab.com project.
+func gitlabSource(name, displayName, projectPath string) Source {
+	return Source{
+		Name:        name,
+		DisplayName: displayName,
+		FetchFunc: func() ([]ChangelogEntry, error) {
+			return fetchGitLabReleases(projectPath)
+		},
+		Parser:      "gitlab-releases",
+		RequestURLs: gitlabReleaseURLs(projectPath),
+		Repo:        projectPath,
+		Homepage:    "https://gitlab.com/" + projectPath,
+		Kind:        "gitlab-releases",
+		Changelog:   "https://gitlab.com/" + projectPath + "/-/releases",
+	}
+}
+
+func fetchGitLabReleases(projectPath string) ([]ChangelogEntry, error) {
+	prog := newPageProgress(showPageProgress && maxPages > 1)
+	defer prog.clear()
+
+	var entries []ChangelogEntry
+	for page := 1; page <= maxPages; page++ {
+		pageEntries, links, err := fetchGitLabReleasesPage(projectPath, page)
+		if err != nil {
+			if page == 1 {
+				return nil, err
+			}
+			return dedupeVersions(entries), fmt.Errorf("releases page %d: %w", page, err)
+		}
+		entries = append(entries, pageEntries...)
+		if !links.hasNext {
+			break
+		}
+		total := 0
+		if links.last > 0 {
+			total = min(links.last, maxPages)
+		}
+		prog.page(page, total)
+	}
+	return dedupeVersions(entries), nil
+}
+
+// fetchGitLabReleasesPage fetches one page of releases and reports what the
+// API's Link header says about the pages after it.
+func fetchGitLabReleasesPage(projectPath string, page int) ([]ChangelogEntry, pageLinks, error) {
+	url := gitlabReleasesPageURL(projectPath, page)
+
+	req, err := newRequest(url)
+	if err != nil {
+		return nil, pageLinks{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, pageLinks{}, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, pageLinks{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// GitLab reports errors as {"message": ...}, like GitHub.
+		if msg := githubErrorMessage(body); msg != "" {
+			return nil, pageLinks{}, fmt.Errorf("GitLab API error (HTTP %d): %s", resp.StatusCode, msg)
+		}
+		return nil, pageLinks{}, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	links := parsePageLinks(resp.Header.Get("Link"))
+	entries, err := cachedParse(url, body, func() ([]ChangelogEntry, error) {
+		return parseGitLabReleases(body)
+	})
+	return entries, links, err
+}
+
+// parseGitLabReleases turns a GitLab releases API response into entries.
+func parseGitLabReleases(body []byte) ([]ChangelogEntry, error) {
+	var releases []struct {
+		TagName     string `json:"tag_name"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		ReleasedAt  string `json:"released_at"`
+		Commit      struct {
+			ID string `json:"id"`
+		} `json:"commit"`
+		Links struct {
+			Self string `json:"self"`
+		} `json:"_links"`
+	}
+
+	if err := json.Unmarshal(body, &releases); err != nil {
+		if msg := githubErrorMessage(body); msg != "" {
+			return nil, fmt.Errorf("GitLab API error: %s", msg)
+		}
+		return nil, fmt.Errorf("failed to parse releases: %w", err)
+	}
+
+	var entries []ChangelogEntry
+	for _, rel := range releases {
+		sections, ungroupedChanges := parseReleaseBody(rel.Description)
+
+		releasedAt, _ := time.Parse(time.RFC3339, rel.ReleasedAt)
+		if releaseDateFrom == "name" {
+			if named := parseReleaseNameDate(rel.Name, rel.Description); !named.IsZero() {
+				releasedAt = named
+			}
+		}
+
+		var commit, rawBody string
+		if includeCommits {
+			commit = rel.Commit.ID
+		}
+		if includeRaw {
+			rawBody = rel.Description
+		}
+
+		entries = append(entries, ChangelogEntry{
+			Version:    trimTagPrefix(rel.TagName),
+			ReleasedAt: releasedAt,
+			Sections:   sections,
+			Changes:    ungroupedChanges,
+			Commit:     commit,
+			RawBody:    rawBody,
+			URL:        rel.Links.Self,
+		})
+	}
+
+	return entries, nil
+}
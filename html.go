@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// errFormatChanged is returned when a scraped page no longer has the layout
+// its parser expects.
+var errFormatChanged = errors.New("page layout not recognized; the source may have changed its format")
+
+var (
+	htmlTagRegex     = regexp.MustCompile(`(?s)<[^>]*>`)
+	htmlCommentRegex = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlScriptRegex  = regexp.MustCompile(`(?is)<(script|style)\b.*?</(script|style)>`)
+	htmlDateAttr     = regexp.MustCompile(`datetime="(\d{4}-\d{2}-\d{2})`)
+	htmlDateText     = regexp.MustCompile(`\b((?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]*\.? \d{1,2}, \d{4})\b`)
+)
+
+// htmlElements returns the inner HTML of every <tag> element in doc. Nested
+// elements of the same tag aren't supported; the first closing tag ends the
+// element.
+func htmlElements(doc, tag string) []string {
+	re := regexp.MustCompile(`(?is)<` + tag + `\b[^>]*>(.*?)</` + tag + `\s*>`)
+	var elements []string
+	for _, match := range re.FindAllStringSubmatch(doc, -1) {
+		elements = append(elements, match[1])
+	}
+	return elements
+}
+
+// htmlText converts an HTML fragment to plain text: tags are removed,
+// entities decoded and whitespace collapsed.
+func htmlText(fragment string) string {
+	fragment = htmlScriptRegex.ReplaceAllString(fragment, " ")
+	fragment = htmlCommentRegex.ReplaceAllString(fragment, " ")
+	fragment = htmlTagRegex.ReplaceAllString(fragment, " ")
+	return strings.Join(strings.Fields(html.UnescapeString(fragment)), " ")
+}
+
+// htmlDate finds a publication date in an HTML fragment, from a datetime
+// attribute or text such as "January 2, 2006". It returns the zero time if
+// none is found.
+func htmlDate(fragment string) time.Time {
+	if match := htmlDateAttr.FindStringSubmatch(fragment); match != nil {
+		if t, err := time.Parse("2006-01-02", match[1]); err == nil {
+			return t
+		}
+	}
+	if match := htmlDateText.FindStringSubmatch(htmlText(fragment)); match != nil {
+		text := strings.Replace(match[1], ".", "", 1)
+		for _, layout := range []string{"January 2, 2006", "Jan 2, 2006"} {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
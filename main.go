@@ -1,23 +1,89 @@
 package main
 
 import (
+	"cmp"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
 	"regexp"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 var version = "dev"
 
+// githubHost is the GitHub instance used by GitHub-backed sources. It can be
+// pointed at a GitHub Enterprise server with -github-host or AIC_GITHUB_HOST.
+var githubHost = "github.com"
+
+// releaseDateFrom selects where GitHub release dates come from: "published"
+// uses the release's published_at, "name" prefers a date written in the
+// release name or first line of its body.
+var releaseDateFrom = "published"
+
+// jsonErrors makes fatal errors print as JSON objects; it is set by -json.
+var jsonErrors bool
+
+// quiet suppresses progress output on stderr.
+var quiet bool
+
+// showPageProgress reports each page of paginated GitHub fetches on stderr.
+// It is only set for single-source fetches, which draw no other progress.
+var showPageProgress bool
+
+// userAgent is sent with every request. It defaults to aic/<version> and can
+// be set with -user-agent or AIC_USER_AGENT.
+var userAgent string
+
+// tagPrefixes are removed from GitHub release tags to form versions. The
+// config file can replace them.
+var tagPrefixes = []string{"v", "rust-v"}
+
+// keepTag uses release tags as versions without removing a prefix.
+var keepTag bool
+
+// maxPages is how many pages of GitHub releases to fetch.
+var maxPages = 1
+
+// includeCommits makes GitHub sources record the commit SHA of each release tag.
+var includeCommits bool
+
+// includeRaw makes GitHub sources keep each release's unparsed body.
+var includeRaw bool
+
+// includeUnreleased keeps the Unreleased section of Keep a Changelog files as
+// an undated entry with the version "Unreleased".
+var includeUnreleased bool
+
+// backfillDates makes markdown sources date their entries from the commits of
+// the repository's matching tags.
+var backfillDates bool
+
 type Section struct {
 	Name    string   `json:"name"`
 	Changes []string `json:"changes"`
+
+	// OriginalName is the source's own name for a section renamed by
+	// -normalize-sections.
+	OriginalName string `json:"original_name,omitempty"`
+}
+
+// Asset is a downloadable file attached to a GitHub release.
+type Asset struct {
+	Name        string `json:"name"`
+	DownloadURL string `json:"download_url"`
 }
 
 type ChangelogEntry struct {
@@ -26,12 +92,45 @@ type ChangelogEntry struct {
 	Source     string    `json:"source,omitempty"`
 	Sections   []Section `json:"sections,omitempty"`
 	Changes    []string  `json:"changes,omitempty"`
+	Assets     []Asset   `json:"assets,omitempty"`
+	Commit     string    `json:"commit,omitempty"`
+	RawBody    string    `json:"raw_body,omitempty"`
+	URL        string    `json:"url,omitempty"`
+
+	// VersionFrom is the lowest version of an entry whose heading covers a
+	// range, such as "## 1.2.0 - 1.2.3"; Version is then the highest.
+	VersionFrom string `json:"version_from,omitempty"`
+
+	// omitted counts changes dropped by -head, for formatters to mention.
+	omitted int
 }
 
 type Source struct {
 	Name        string
 	DisplayName string
 	FetchFunc   func() ([]ChangelogEntry, error)
+
+	// Parser names the parser FetchFunc applies, and RequestURLs lists the
+	// URLs it requests. Both are only reported by -dry-run.
+	Parser      string
+	RequestURLs func() []string
+
+	// URLs lists the markdown changelogs, URLs or local paths, that a
+	// source's FetchFunc reads with fetchMarkdownChangelogs. Projects that
+	// move old entries to an archive such as CHANGELOG-OLD.md list it after
+	// the current file, and its entries extend the history.
+	URLs []string
+
+	// Repo ("owner/name") and Homepage say where a source's changelog comes
+	// from, and Kind how it is published: github-releases, gitlab-releases,
+	// markdown-raw or html.
+	Repo     string
+	Homepage string
+	Kind     string
+
+	// Changelog is the page people read the changelog on, which -open falls
+	// back to for entries without a URL of their own.
+	Changelog string
 }
 
 var sources = map[string]Source{
@@ -39,284 +138,1976 @@ var sources = map[string]Source{
 		Name:        "claude",
 		DisplayName: "Claude Code",
 		FetchFunc:   fetchClaudeChangelog,
+		Parser:      "markdown",
+		RequestURLs: claudeChangelogURLs,
+		Repo:        "anthropics/claude-code",
+		Homepage:    "https://github.com/anthropics/claude-code",
+		Kind:        "markdown-raw",
+		Changelog:   "https://github.com/anthropics/claude-code/blob/main/CHANGELOG.md",
 	},
 	"codex": {
 		Name:        "codex",
 		DisplayName: "OpenAI Codex",
 		FetchFunc:   fetchCodexChangelog,
+		Parser:      "github-releases",
+		RequestURLs: githubReleaseURLs("openai", "codex"),
+		Repo:        "openai/codex",
+		Homepage:    "https://github.com/openai/codex",
+		Kind:        "github-releases",
+		Changelog:   "https://github.com/openai/codex/releases",
 	},
 	"opencode": {
 		Name:        "opencode",
 		DisplayName: "OpenCode",
 		FetchFunc:   fetchOpenCodeChangelog,
+		Parser:      "github-releases",
+		RequestURLs: githubReleaseURLs("sst", "opencode"),
+		Repo:        "sst/opencode",
+		Homepage:    "https://opencode.ai",
+		Kind:        "github-releases",
+		Changelog:   "https://github.com/sst/opencode/releases",
 	},
 	"gemini": {
 		Name:        "gemini",
 		DisplayName: "Gemini CLI",
 		FetchFunc:   fetchGeminiChangelog,
+		Parser:      "github-releases",
+		RequestURLs: githubReleaseURLs("google-gemini", "gemini-cli"),
+		Repo:        "google-gemini/gemini-cli",
+		Homepage:    "https://github.com/google-gemini/gemini-cli",
+		Kind:        "github-releases",
+		Changelog:   "https://github.com/google-gemini/gemini-cli/releases",
 	},
 	"copilot": {
 		Name:        "copilot",
 		DisplayName: "GitHub Copilot CLI",
 		FetchFunc:   fetchCopilotChangelog,
+		Parser:      "markdown",
+		RequestURLs: func() []string { return []string{copilotChangelogURL()} },
+		Repo:        "github/copilot-cli",
+		Homepage:    "https://github.com/github/copilot-cli",
+		Kind:        "markdown-raw",
+		Changelog:   "https://github.com/github/copilot-cli/blob/main/changelog.md",
+	},
+	"goose": {
+		Name:        "goose",
+		DisplayName: "Goose",
+		FetchFunc:   fetchGooseChangelog,
+		Parser:      "github-releases",
+		RequestURLs: githubReleaseURLs("block", "goose"),
+		Repo:        "block/goose",
+		Homepage:    "https://block.github.io/goose",
+		Kind:        "github-releases",
+		Changelog:   "https://github.com/block/goose/releases",
+	},
+	"roo": {
+		Name:        "roo",
+		DisplayName: "Roo Code",
+		FetchFunc:   fetchRooChangelog,
+		Parser:      "github-releases",
+		RequestURLs: githubReleaseURLs("RooCodeInc", "Roo-Code"),
+		Repo:        "RooCodeInc/Roo-Code",
+		Homepage:    "https://roocode.com",
+		Kind:        "github-releases",
+		Changelog:   "https://github.com/RooCodeInc/Roo-Code/releases",
+	},
+	"amp": {
+		Name:        "amp",
+		DisplayName: "Amp",
+		FetchFunc:   fetchAmpChangelog,
+		Parser:      "amp-news",
+		RequestURLs: func() []string { return []string{ampNewsURL} },
+		Homepage:    "https://ampcode.com",
+		Kind:        "html",
+		Changelog:   ampNewsURL,
 	},
 }
 
 func main() {
-	args := os.Args[1:]
+	for _, arg := range os.Args[1:] {
+		if arg == "-json" || arg == "--json" {
+			jsonErrors = true
+		}
+	}
 
-	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+	args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "--help" || args[0] == "help") {
 		printUsage()
 		os.Exit(0)
 	}
 
-	if args[0] == "-v" || args[0] == "--version" {
+	if len(args) > 0 && (args[0] == "-v" || args[0] == "--version") {
 		fmt.Printf("aic version %s\n", version)
 		os.Exit(0)
 	}
 
+	if printCachePath {
+		dir, err := cacheDir()
+		if err != nil {
+			fatalf("%v", err)
+		}
+		fmt.Println(dir)
+		os.Exit(0)
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		fatalf("failed to load config: %v", err)
+	}
+	if cfg.TagPrefixes != nil {
+		tagPrefixes = cfg.TagPrefixes
+	}
+	githubLimiter = newRateLimiter(cfg.GitHubRate)
+	for _, src := range cfg.CustomSources {
+		if _, ok := sources[src.Name]; ok {
+			fatalf("config source '%s' clashes with a built-in source", src.Name)
+		}
+		sources[src.Name] = src
+	}
+
+	// With no source given, use the default source if one is set; flags
+	// then apply to it.
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		defaultSource := os.Getenv("AIC_DEFAULT_SOURCE")
+		if defaultSource == "" {
+			defaultSource = cfg.DefaultSource
+		}
+		if defaultSource != "" {
+			args = append([]string{defaultSource}, args...)
+		} else if len(args) == 0 {
+			printUsage()
+			os.Exit(0)
+		}
+	}
+
 	if args[0] == "list-sources" {
+		if slices.Contains(args[1:], "-json") || slices.Contains(args[1:], "--json") {
+			outputSourcesJSON(cfg)
+			os.Exit(0)
+		}
 		for name, src := range sources {
 			fmt.Printf("  %s\t%s\n", name, src.DisplayName)
 		}
+		if len(cfg.Aliases) > 0 {
+			fmt.Println("\nAliases:")
+			for alias, target := range cfg.Aliases {
+				fmt.Printf("  %s\t%s\n", alias, target)
+			}
+		}
+		os.Exit(0)
+	}
+
+	if args[0] == "completions" {
+		if len(args) < 2 {
+			fatalf("completions requires a shell (bash, zsh, fish)")
+		}
+		if err := writeCompletions(os.Stdout, args[1]); err != nil {
+			fatalf("%v", err)
+		}
 		os.Exit(0)
 	}
 
+	if args[0] == "cache" {
+		os.Exit(runCacheCommand(args[1:]))
+	}
+
+	if args[0] == "tui" {
+		os.Exit(runTUI())
+	}
+
+	if args[0] == "diff" {
+		os.Exit(runDiffCommand(args[1:], cfg))
+	}
+
+	if args[0] == "compare" {
+		os.Exit(runCompareCommand(args[1:], cfg))
+	}
+
+	if args[0] == "digest" {
+		os.Exit(runDigestCommand(args[1:], cfg))
+	}
+
+	if args[0] == "watch" {
+		os.Exit(runWatchCommand(args[1:], cfg))
+	}
+
+	if args[0] == "serve" {
+		os.Exit(runServe(args[1:], cfg))
+	}
+
 	if args[0] == "latest" {
-		var jsonOutput bool
+		var opts latestOptions
+		format := newFormatFlags(&opts.outputOptions)
 		for i := 1; i < len(args); i++ {
-			if args[i] == "-json" || args[i] == "--json" {
-				jsonOutput = true
+			switch args[i] {
+			case "-json", "--json":
+				opts.jsonOutput = true
+			case "-summary", "--summary":
+				opts.summary = true
+			case "-dedupe-across", "--dedupe-across":
+				opts.dedupeAcross = true
+			case "-strict", "--strict":
+				opts.strict = true
+			case "-sources", "--sources":
+				if i+1 < len(args) {
+					opts.sources = splitList(args[i+1])
+					i++
+				}
+			case "-exclude-sources", "--exclude-sources":
+				if i+1 < len(args) {
+					opts.excludeSources = splitList(args[i+1])
+					i++
+				}
+			case "-jsonl-file", "--jsonl-file":
+				if i+1 < len(args) {
+					opts.jsonlFile = args[i+1]
+					i++
+				}
+			default:
+				i = format.parse(args, i)
 			}
 		}
-		runLatestCommand(jsonOutput)
+		format.finish(opts.jsonOutput)
+		if opts.sources == nil {
+			opts.sources = cfg.LatestSources
+		}
+		for i, name := range opts.sources {
+			opts.sources[i] = cfg.resolveAlias(name)
+		}
+		for i, name := range opts.excludeSources {
+			opts.excludeSources[i] = cfg.resolveAlias(name)
+		}
+		runLatestCommand(opts)
 		os.Exit(0)
 	}
 
-	sourceName := args[0]
-	source, ok := sources[sourceName]
-	if !ok {
-		fmt.Fprintf(os.Stderr, "Error: Unknown source '%s'\n\n", sourceName)
-		fmt.Fprintf(os.Stderr, "Available sources:\n")
-		for name := range sources {
-			fmt.Fprintf(os.Stderr, "  %s\n", name)
+	var source Source
+	var multiSources []Source
+	var allSources bool
+	var parserName, pattern, gistFile string
+
+	if args[0] == "file" {
+		if len(args) < 2 {
+			fatalf("file requires a path or URL")
 		}
-		os.Exit(1)
+		// Further locations, such as an archived CHANGELOG-OLD.md, extend
+		// the first.
+		var locations []string
+		for _, arg := range args[1:] {
+			if strings.HasPrefix(arg, "-") {
+				break
+			}
+			locations = append(locations, arg)
+		}
+		source = Source{
+			Name:        "file",
+			DisplayName: path.Base(locations[0]),
+			FetchFunc: func() ([]ChangelogEntry, error) {
+				return fetchMarkdownChangelogs(locations, parserName, pattern)
+			},
+			RequestURLs: func() []string { return locations },
+			URLs:        locations,
+		}
+		args = args[len(locations):]
+	} else if args[0] == "gist" {
+		if len(args) < 2 {
+			fatalf("gist requires a gist ID")
+		}
+		id := args[1]
+		source = Source{
+			Name:        "gist",
+			DisplayName: "Gist " + id,
+			FetchFunc: func() ([]ChangelogEntry, error) {
+				return fetchGistChangelog(id, gistFile, parserName, pattern)
+			},
+			RequestURLs: func() []string { return []string{gistURL(id)} },
+			Changelog:   "https://gist.github.com/" + id,
+		}
+		args = args[1:]
+	} else if args[0] == "all" {
+		// The sources are picked once -sources and -exclude-sources are
+		// parsed.
+		allSources = true
+	} else {
+		// Leading positional arguments name the sources to show.
+		var names []string
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-") {
+				break
+			}
+			names = append(names, arg)
+		}
+
+		for _, name := range names {
+			sourceName := cfg.resolveAlias(name)
+			src, ok := sources[sourceName]
+			if ok {
+				multiSources = append(multiSources, src)
+				continue
+			}
+			if len(names) > 1 {
+				fmt.Fprintf(os.Stderr, "Warning: Unknown source '%s', skipping\n", sourceName)
+				continue
+			}
+			if jsonErrors {
+				fatalf("Unknown source '%s'", sourceName)
+			}
+			fmt.Fprintf(os.Stderr, "Error: Unknown source '%s'\n\n", sourceName)
+			if suggestion := suggestSource(sourceName); suggestion != "" {
+				fmt.Fprintf(os.Stderr, "Did you mean '%s'?\n\n", suggestion)
+			}
+			fmt.Fprintf(os.Stderr, "Available sources:\n")
+			for name := range sources {
+				fmt.Fprintf(os.Stderr, "  %s\n", name)
+			}
+			os.Exit(1)
+		}
+
+		if len(multiSources) == 0 {
+			fatalf("No known sources given")
+		}
+		source = multiSources[0]
 	}
 
-	var jsonOutput, mdOutput, listVersions bool
-	var targetVersion string
+	var jsonOutput, mdOutput, shellOutput, listVersions, versionLatest, tocOutput, countsOutput, allEntries, ifChanged, dryRun, openPage bool
+	var opts outputOptions
+	format := newFormatFlags(&opts)
+	var targetVersion, currentVersion, sinceVersion, outputDir string
+	var asOf, since, until time.Time
+	var maxAge time.Duration
+	var limit int
+	var tmpl *template.Template
+	var include, exclude []string
 
 	for i := 1; i < len(args); i++ {
 		switch args[i] {
 		case "-json", "--json":
 			jsonOutput = true
+		case "-sources", "--sources":
+			if i+1 < len(args) {
+				include = splitList(args[i+1])
+				i++
+			}
+		case "-exclude-sources", "--exclude-sources":
+			if i+1 < len(args) {
+				exclude = splitList(args[i+1])
+				i++
+			}
 		case "-md", "--md":
 			mdOutput = true
+		case "-shell", "--shell":
+			shellOutput = true
+		case "-template", "--template":
+			if i+1 < len(args) {
+				t, err := parseOutputTemplate("-template", args[i+1])
+				if err != nil {
+					fatalf("%v", err)
+				}
+				tmpl = t
+				i++
+			}
+		case "-template-file", "--template-file":
+			if i+1 < len(args) {
+				t, err := loadTemplateFile(args[i+1])
+				if err != nil {
+					fatalf("-template-file: %v", err)
+				}
+				tmpl = t
+				i++
+			}
+		case "-front-matter", "--front-matter":
+			opts.frontMatter = true
+		case "-md-no-date", "--md-no-date":
+			opts.mdNoDate = true
+		case "-md-no-sections", "--md-no-sections":
+			opts.mdNoSections = true
+		case "-strip-pr-links", "--strip-pr-links":
+			opts.stripPRLinks = true
+		case "-no-ungrouped", "--no-ungrouped":
+			opts.noUngrouped = true
+		case "-only-ungrouped", "--only-ungrouped":
+			opts.onlyUngrouped = true
 		case "-list", "--list":
 			listVersions = true
+		case "-all", "--all":
+			allEntries = true
+		case "-n":
+			if i+1 < len(args) {
+				limit = parsePositiveFlag("-n", args[i+1])
+				allEntries = true
+				i++
+			}
+		case "-toc", "--toc":
+			tocOutput = true
+		case "-category-counts", "--category-counts":
+			countsOutput = true
+		case "-version-latest", "--version-latest":
+			versionLatest = true
 		case "-version", "--version":
 			if i+1 < len(args) {
 				targetVersion = args[i+1]
 				i++
 			}
+		case "-current", "--current":
+			if i+1 < len(args) {
+				currentVersion = args[i+1]
+				i++
+			}
+		case "-since-version", "--since-version":
+			if i+1 < len(args) {
+				sinceVersion = args[i+1]
+				i++
+			}
+		case "-parser", "--parser":
+			if i+1 < len(args) {
+				parserName = args[i+1]
+				i++
+			}
+		case "-pattern", "--pattern":
+			if i+1 < len(args) {
+				pattern = args[i+1]
+				i++
+			}
+		case "-file", "--file":
+			if i+1 < len(args) {
+				gistFile = args[i+1]
+				i++
+			}
+		case "-dry-run", "--dry-run":
+			dryRun = true
+		case "-open", "--open":
+			openPage = true
+		case "-output-dir", "--output-dir":
+			if i+1 < len(args) {
+				outputDir = args[i+1]
+				i++
+			}
+		case "-if-changed", "--if-changed":
+			ifChanged = true
+		case "-as-of", "--as-of":
+			if i+1 < len(args) {
+				asOf = parseDateFlag("-as-of", args[i+1])
+				i++
+			}
+		case "-since", "--since":
+			if i+1 < len(args) {
+				since = parseSinceFlag(args[i+1])
+				i++
+			}
+		case "-max-age", "--max-age":
+			if i+1 < len(args) {
+				age, ok := parseAge(args[i+1])
+				if !ok || age == 0 {
+					fatalf("-max-age expects an age (90d, 12w, 720h), got '%s'", args[i+1])
+				}
+				maxAge = age
+				i++
+			}
+		case "-until", "--until":
+			if i+1 < len(args) {
+				until = parseDateFlag("-until", args[i+1])
+				i++
+			}
+		default:
+			i = format.parse(args, i)
 		}
 	}
 
-	entries, err := source.FetchFunc()
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error fetching changelog: %v\n", err)
-		os.Exit(1)
+	if allSources {
+		multiSources = sortedSources(cfg, include, exclude)
+		if len(multiSources) == 0 {
+			fatalf("-sources and -exclude-sources leave no sources to show")
+		}
+		source = multiSources[0]
+	} else if include != nil || exclude != nil {
+		fatalf("-sources and -exclude-sources pick sources for all, as in: aic all -exclude-sources copilot")
 	}
 
-	if len(entries) == 0 {
-		fmt.Fprintf(os.Stderr, "Error: No changelog entries found\n")
-		os.Exit(1)
-	}
+	format.finish(jsonOutput)
 
-	if listVersions {
-		for _, entry := range entries {
-			fmt.Println(entry.Version)
+	formats := 0
+	for _, set := range []bool{jsonOutput, mdOutput, shellOutput, tmpl != nil} {
+		if set {
+			formats++
 		}
-		os.Exit(0)
 	}
-
-	var entry *ChangelogEntry
-	if targetVersion != "" {
-		for i := range entries {
-			if entries[i].Version == targetVersion {
-				entry = &entries[i]
-				break
-			}
-		}
-		if entry == nil {
-			fmt.Fprintf(os.Stderr, "Error: Version %s not found\n", targetVersion)
-			os.Exit(1)
-		}
-	} else {
-		entry = &entries[0]
+	if formats > 1 {
+		fatalf("only one output format may be specified")
 	}
 
-	if jsonOutput {
-		outputJSON(entry)
-	} else if mdOutput {
-		outputMarkdown(entry)
-	} else {
-		outputPlainText(source.DisplayName, entry)
+	if opts.noUngrouped && opts.onlyUngrouped {
+		fatalf("-no-ungrouped and -only-ungrouped cannot be combined")
 	}
-}
-
-func printUsage() {
-	fmt.Fprintf(os.Stderr, "aic - AI Coding Agent Changelog Viewer\n\n")
-	fmt.Fprintf(os.Stderr, "Usage: aic <source> [flags]\n")
-	fmt.Fprintf(os.Stderr, "       aic latest [flags]\n\n")
-	fmt.Fprintf(os.Stderr, "Sources:\n")
-	fmt.Fprintf(os.Stderr, "  claude      Claude Code (Anthropic)\n")
-	fmt.Fprintf(os.Stderr, "  codex       Codex CLI (OpenAI)\n")
-	fmt.Fprintf(os.Stderr, "  opencode    OpenCode (SST)\n")
-	fmt.Fprintf(os.Stderr, "  gemini      Gemini CLI (Google)\n")
-	fmt.Fprintf(os.Stderr, "  copilot     Copilot CLI (GitHub)\n\n")
-	fmt.Fprintf(os.Stderr, "Commands:\n")
-	fmt.Fprintf(os.Stderr, "  latest             Show releases from all sources in last 24h\n\n")
-	fmt.Fprintf(os.Stderr, "Flags:\n")
-	fmt.Fprintf(os.Stderr, "  -json              Output as JSON\n")
-	fmt.Fprintf(os.Stderr, "  -md                Output as markdown\n")
-	fmt.Fprintf(os.Stderr, "  -list              List all versions\n")
-	fmt.Fprintf(os.Stderr, "  -version <ver>     Get specific version\n")
-	fmt.Fprintf(os.Stderr, "  -v, --version      Show aic version\n")
-	fmt.Fprintf(os.Stderr, "  -h, --help         Show this help\n\n")
-	fmt.Fprintf(os.Stderr, "Examples:\n")
-	fmt.Fprintf(os.Stderr, "  aic claude                    # Latest Claude Code entry\n")
-	fmt.Fprintf(os.Stderr, "  aic codex -json               # Latest Codex entry as JSON\n")
-	fmt.Fprintf(os.Stderr, "  aic opencode -list            # List OpenCode versions\n")
-	fmt.Fprintf(os.Stderr, "  aic gemini -version 0.21.0    # Specific Gemini version\n")
-	fmt.Fprintf(os.Stderr, "  aic latest                    # All releases in last 24h\n")
-}
-
-func runLatestCommand(jsonOutput bool) {
-	cutoff := time.Now().Add(-24 * time.Hour)
 
-	type result struct {
-		source  string
-		display string
-		entry   *ChangelogEntry
-		err     error
+	if allEntries && tocOutput && jsonOutput {
+		fatalf("-toc -json cannot be combined with -all")
 	}
 
-	results := make(chan result, len(sources))
-	var wg sync.WaitGroup
-
-	for name, src := range sources {
-		wg.Add(1)
-		go func(name string, src Source) {
-			defer wg.Done()
-			entries, err := src.FetchFunc()
-			if err != nil {
-				results <- result{source: name, display: src.DisplayName, err: err}
-				return
-			}
-			if len(entries) > 0 {
-				entry := entries[0]
-				entry.Source = src.DisplayName
-				results <- result{source: name, display: src.DisplayName, entry: &entry}
-			}
-		}(name, src)
+	if dryRun {
+		srcs := multiSources
+		if len(srcs) == 0 {
+			srcs = []Source{source}
+		}
+		for _, src := range srcs {
+			printDryRun(src, parserName, pattern)
+		}
+		os.Exit(0)
 	}
 
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+	if ifChanged && len(multiSources) > 1 {
+		fatalf("-if-changed only works with a single source")
+	}
+	if !asOf.IsZero() && len(multiSources) > 1 {
+		fatalf("-as-of only works with a single source")
+	}
+	if (!since.IsZero() || !until.IsZero()) && len(multiSources) > 1 {
+		fatalf("-since and -until only work with a single source")
+	}
+	if sinceVersion != "" && len(multiSources) > 1 {
+		fatalf("-since-version only works with a single source")
+	}
+	if shellOutput && len(multiSources) > 1 {
+		fatalf("-shell only works with a single source")
+	}
+	if tmpl != nil && len(multiSources) > 1 {
+		fatalf("-template only works with a single source")
+	}
 
-	var recentEntries []ChangelogEntry
-	for r := range results {
-		if r.err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch %s: %v\n", r.display, r.err)
-			continue
+	if outputDir != "" {
+		if !mdOutput {
+			fatalf("-output-dir requires -md")
 		}
-		if r.entry != nil && !r.entry.ReleasedAt.IsZero() && r.entry.ReleasedAt.After(cutoff) {
-			recentEntries = append(recentEntries, *r.entry)
+		srcs := multiSources
+		if len(srcs) == 0 {
+			srcs = []Source{source}
 		}
+		os.Exit(runOutputDir(srcs, outputDir, targetVersion, allEntries, limit, opts))
 	}
 
-	// Sort by release date descending
-	sort.Slice(recentEntries, func(i, j int) bool {
-		return recentEntries[i].ReleasedAt.After(recentEntries[j].ReleasedAt)
-	})
-
-	if len(recentEntries) == 0 {
-		fmt.Println("No releases in the last 24 hours.")
-		return
+	if allSources && jsonOutput && !listVersions {
+		os.Exit(runAllJSON(multiSources, targetVersion, maxAge, opts))
 	}
 
-	if jsonOutput {
-		encoder := json.NewEncoder(os.Stdout)
-		encoder.SetIndent("", "  ")
-		encoder.Encode(recentEntries)
-	} else {
-		for i, entry := range recentEntries {
-			if i > 0 {
-				fmt.Println()
-			}
-			outputPlainText(entry.Source, &entry)
-		}
+	if len(multiSources) > 1 {
+		os.Exit(runMultiSource(multiSources, targetVersion, listVersions, jsonOutput, mdOutput, maxAge, opts))
 	}
-}
 
-func fetchClaudeChangelog() ([]ChangelogEntry, error) {
-	url := "https://raw.githubusercontent.com/anthropics/claude-code/main/CHANGELOG.md"
-	content, err := httpGet(url)
+	showPageProgress = true
+	entries, err := fetchSource(source)
+	reportCacheStats()
 	if err != nil {
-		return nil, err
+		fatalf("failed to fetch changelog: %v", err)
 	}
 
-	// Regex: ## 1.2.3 or ## 1.2.3 (2024-01-07)
-	entries := parseMarkdownChangelogWithOptionalDate(content, `(?m)^## (\d+\.\d+\.\d+)(?:\s+\((\d{4}-\d{2}-\d{2})\))?\s*$`)
+	if len(entries) == 0 {
+		fatalf("No changelog entries found")
+	}
+	warnIfStale(source, entries, maxAge)
 
-	if len(entries) > 0 && entries[0].ReleasedAt.IsZero() {
-		commitDate := fetchGitHubFileLastCommitDate("anthropics", "claude-code", "CHANGELOG.md")
-		if !commitDate.IsZero() {
-			entries[0].ReleasedAt = commitDate
+	if !asOf.IsZero() {
+		entries = entriesAsOf(entries, asOf)
+		if len(entries) == 0 {
+			fatalf("No entries released on or before %s", asOf.Format("2006-01-02"))
 		}
 	}
 
-	return entries, nil
-}
-
-func fetchGitHubFileLastCommitDate(owner, repo, path string) time.Time {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/commits?path=%s&per_page=1", owner, repo, path)
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return time.Time{}
+	if !since.IsZero() || !until.IsZero() {
+		entries = entriesBetween(entries, since, until)
+		if len(entries) == 0 && !listVersions {
+			fatalf("No entries released in the given window")
+		}
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("User-Agent", "aic-changelog")
 
-	resp, err := http.DefaultClient.Do(req)
+	if sinceVersion != "" {
+		if selectEntry(entries, sinceVersion) == nil {
+			fatalf("Version %s not found", sinceVersion)
+		}
+		entries = entriesNewerThan(entries, sinceVersion)
+		if len(entries) == 0 {
+			if jsonOutput {
+				outputJSONArray(os.Stdout, nil)
+			} else {
+				fmt.Printf("No releases newer than %s\n", sinceVersion)
+			}
+			os.Exit(0)
+		}
+		allEntries = true
+	}
+
+	// -list may have nothing left to show; there is then nothing to mark.
+	if ifChanged && len(entries) > 0 {
+		changed, err := markSeen(source, entries[0].Version)
+		if err != nil {
+			fatalf("failed to update state: %v", err)
+		}
+		if !changed {
+			os.Exit(0)
+		}
+	}
+
+	if listVersions {
+		for _, entry := range entries {
+			fmt.Println(entry.Version)
+		}
+		os.Exit(0)
+	}
+
+	if versionLatest {
+		fmt.Println(entries[0].Version)
+		os.Exit(0)
+	}
+
+	if currentVersion != "" {
+		os.Exit(runCurrentCheck(currentVersion, entries[0].Version, jsonOutput))
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+
+	if allEntries {
+		shown := make([]ChangelogEntry, len(entries))
+		for i := range entries {
+			shown[i] = applyOutputOptions(entries[i], opts)
+		}
+
+		if countsOutput {
+			outputCategoryCountsArray(os.Stdout, shown)
+		} else if jsonOutput && !tocOutput {
+			if err := outputJSONArray(os.Stdout, shown); err != nil {
+				fatalf("encoding JSON: %v", err)
+			}
+		} else if shellOutput && !tocOutput {
+			for i := range shown {
+				outputShell(os.Stdout, &shown[i])
+			}
+		} else if tmpl != nil && !tocOutput {
+			for i := range shown {
+				if err := outputTemplate(os.Stdout, tmpl, source.DisplayName, shown[i]); err != nil {
+					fatalf("%v", err)
+				}
+			}
+		} else {
+			for i := range shown {
+				if i > 0 {
+					fmt.Println()
+				}
+				if tocOutput {
+					outputTOC(source.DisplayName, &shown[i], false, mdOutput, opts)
+				} else if mdOutput {
+					// Entries may end with a blank line of their own; keep
+					// exactly one between them.
+					var b strings.Builder
+					outputMarkdown(&b, source.DisplayName, &shown[i], opts)
+					fmt.Println(strings.TrimRight(b.String(), "\n"))
+				} else {
+					outputPlainText(source.Name, source.DisplayName, &shown[i], opts)
+				}
+			}
+		}
+		os.Exit(0)
+	}
+
+	entry := selectEntry(entries, targetVersion)
+	if entry == nil {
+		fatalf("Version %s not found", targetVersion)
+	}
+
+	filtered := applyOutputOptions(*entry, opts)
+	entry = &filtered
+
+	if countsOutput {
+		outputCategoryCounts(entry)
+	} else if tocOutput {
+		outputTOC(source.DisplayName, entry, jsonOutput, mdOutput, opts)
+	} else if jsonOutput {
+		outputJSON(entry)
+	} else if mdOutput {
+		outputMarkdown(os.Stdout, source.DisplayName, entry, opts)
+	} else if shellOutput {
+		outputShell(os.Stdout, entry)
+	} else if tmpl != nil {
+		if err := outputTemplate(os.Stdout, tmpl, source.DisplayName, *entry); err != nil {
+			fatalf("%v", err)
+		}
+	} else {
+		outputPlainText(source.Name, source.DisplayName, entry, opts)
+	}
+
+	if openPage {
+		page := entry.URL
+		if page == "" {
+			page = source.Changelog
+		}
+		if page == "" {
+			fmt.Fprintf(os.Stderr, "Warning: No page known for %s %s\n", source.DisplayName, entry.Version)
+		} else if err := openBrowser(page); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to open %s: %v\n", page, err)
+		}
+	}
+}
+
+// parseDateFlag parses a YYYY-MM-DD flag value, exiting on anything else.
+func parseDateFlag(flag, value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		fatalf("%s expects a date (YYYY-MM-DD), got '%s'", flag, value)
+	}
+	return t
+}
+
+// entriesAsOf returns the entries released on or before the day date, newest
+// first. Undated entries are dropped.
+func entriesAsOf(entries []ChangelogEntry, date time.Time) []ChangelogEntry {
+	end := date.AddDate(0, 0, 1)
+	var kept []ChangelogEntry
+	for _, entry := range entries {
+		if !entry.ReleasedAt.IsZero() && entry.ReleasedAt.Before(end) {
+			kept = append(kept, entry)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].ReleasedAt.After(kept[j].ReleasedAt)
+	})
+	return kept
+}
+
+// entriesNewerThan returns the entries whose version is newer than ver, in
+// their original order.
+func entriesNewerThan(entries []ChangelogEntry, ver string) []ChangelogEntry {
+	kept := []ChangelogEntry{}
+	for _, entry := range entries {
+		if compareVersions(entry.Version, ver) > 0 {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// entriesBetween returns the entries released from since through the day
+// until, newest first. A zero bound leaves that end of the window open.
+// Undated entries are dropped.
+func entriesBetween(entries []ChangelogEntry, since, until time.Time) []ChangelogEntry {
+	var kept []ChangelogEntry
+	for _, entry := range entries {
+		if entry.ReleasedAt.IsZero() || entry.ReleasedAt.Before(since) {
+			continue
+		}
+		if !until.IsZero() && !entry.ReleasedAt.Before(until.AddDate(0, 0, 1)) {
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return kept[i].ReleasedAt.After(kept[j].ReleasedAt)
+	})
+	return kept
+}
+
+// selectEntry returns the entry matching targetVersion, or the newest entry
+// when targetVersion is empty. A version inside a range heading selects that
+// entry. It returns nil if the version isn't found.
+func selectEntry(entries []ChangelogEntry, targetVersion string) *ChangelogEntry {
+	if targetVersion == "" {
+		return &entries[0]
+	}
+	for i := range entries {
+		if entries[i].Version == targetVersion {
+			return &entries[i]
+		}
+	}
+	for i := range entries {
+		if from := entries[i].VersionFrom; from != "" &&
+			compareVersions(targetVersion, from) >= 0 && compareVersions(targetVersion, entries[i].Version) <= 0 {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// fetchResult is the outcome of fetching one source.
+type fetchResult struct {
+	entries []ChangelogEntry
+	err     error
+}
+
+// fetchEach fetches srcs concurrently, returning their results in order.
+func fetchEach(srcs []Source) []fetchResult {
+	results := make([]fetchResult, len(srcs))
+	var wg sync.WaitGroup
+	for i, src := range srcs {
+		wg.Add(1)
+		go func(i int, src Source) {
+			defer wg.Done()
+			entries, err := fetchSource(src)
+			results[i] = fetchResult{entries: entries, err: err}
+		}(i, src)
+	}
+	wg.Wait()
+	return results
+}
+
+// runMultiSource fetches several sources concurrently and prints each one's
+// selected entry in the order given, or with -list its versions; -list -json
+// prints an object mapping source names to versions. Sources that fail are
+// reported as warnings. It returns the exit code, which is 1 only if nothing was shown.
+func runMultiSource(srcs []Source, targetVersion string, listVersions, jsonOutput, mdOutput bool, maxAge time.Duration, opts outputOptions) int {
+	results := fetchEach(srcs)
+	reportCacheStats()
+
+	var selected []ChangelogEntry
+	var names, displayNames []string
+	// versions maps source names to their versions for -list -json.
+	versions := map[string][]string{}
+	for i, src := range srcs {
+		r := results[i]
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch %s: %v\n", src.DisplayName, r.err)
+			continue
+		}
+		if len(r.entries) == 0 {
+			fmt.Fprintf(os.Stderr, "Warning: No changelog entries found for %s\n", src.DisplayName)
+			continue
+		}
+		warnIfStale(src, r.entries, maxAge)
+
+		if listVersions && jsonOutput {
+			for _, entry := range r.entries {
+				versions[src.Name] = append(versions[src.Name], entry.Version)
+			}
+			names = append(names, src.Name)
+			displayNames = append(displayNames, src.DisplayName)
+			continue
+		}
+		if listVersions {
+			if len(displayNames) > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("%s:\n", src.DisplayName)
+			for _, entry := range r.entries {
+				fmt.Println(entry.Version)
+			}
+			names = append(names, src.Name)
+			displayNames = append(displayNames, src.DisplayName)
+			continue
+		}
+
+		entry := selectEntry(r.entries, targetVersion)
+		if entry == nil {
+			fmt.Fprintf(os.Stderr, "Warning: Version %s not found for %s\n", targetVersion, src.DisplayName)
+			continue
+		}
+		filtered := applyOutputOptions(*entry, opts)
+		filtered.Source = src.DisplayName
+		selected = append(selected, filtered)
+		names = append(names, src.Name)
+		displayNames = append(displayNames, src.DisplayName)
+	}
+
+	if len(displayNames) == 0 {
+		return 1
+	}
+
+	if listVersions && jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(versions)
+		return 0
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(selected)
+		return 0
+	}
+
+	for i := range selected {
+		if i > 0 {
+			fmt.Println()
+		}
+		if mdOutput {
+			fmt.Printf("# %s\n\n", displayNames[i])
+			outputMarkdown(os.Stdout, displayNames[i], &selected[i], opts)
+		} else {
+			outputPlainText(names[i], displayNames[i], &selected[i], opts)
+		}
+	}
+	return 0
+}
+
+// isStale reports whether maxAge is set and the newest dated entry was
+// released longer than maxAge before now. Without dates there is nothing to
+// judge, so such sources are never stale.
+func isStale(entries []ChangelogEntry, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 {
+		return false
+	}
+	var newest time.Time
+	for _, entry := range entries {
+		if entry.ReleasedAt.After(newest) {
+			newest = entry.ReleasedAt
+		}
+	}
+	return !newest.IsZero() && now.Sub(newest) > maxAge
+}
+
+// warnIfStale prints a STALE warning for src when isStale says so.
+func warnIfStale(src Source, entries []ChangelogEntry, maxAge time.Duration) {
+	if isStale(entries, maxAge, time.Now()) {
+		fmt.Fprintf(os.Stderr, "Warning: %s is STALE: no release in %s\n", src.DisplayName, formatAge(maxAge))
+	}
+}
+
+// allResult is one source's value in "aic all -json" output: its selected
+// entry, or the reason there is none. Stale is set by -max-age.
+type allResult struct {
+	*ChangelogEntry
+	Stale bool   `json:"stale,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// runAllJSON fetches srcs concurrently and prints a JSON object mapping each
+// source name to its selected entry. Sources that fail are included with an
+// error field.
+func runAllJSON(srcs []Source, targetVersion string, maxAge time.Duration, opts outputOptions) int {
+	results := make([]allResult, len(srcs))
+	var wg sync.WaitGroup
+	for i, src := range srcs {
+		wg.Add(1)
+		go func(i int, src Source) {
+			defer wg.Done()
+			entries, err := fetchSource(src)
+			switch {
+			case err != nil:
+				results[i].Error = err.Error()
+			case len(entries) == 0:
+				results[i].Error = "no changelog entries found"
+			default:
+				entry := selectEntry(entries, targetVersion)
+				if entry == nil {
+					results[i].Error = fmt.Sprintf("version %s not found", targetVersion)
+					return
+				}
+				filtered := applyOutputOptions(*entry, opts)
+				filtered.Source = src.DisplayName
+				results[i].ChangelogEntry = &filtered
+				results[i].Stale = isStale(entries, maxAge, time.Now())
+			}
+		}(i, src)
+	}
+	wg.Wait()
+	reportCacheStats()
+
+	keyed := map[string]allResult{}
+	for i, src := range srcs {
+		keyed[src.Name] = results[i]
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(keyed); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// sourceInfo describes a source in "aic list-sources -json" output.
+type sourceInfo struct {
+	Name        string   `json:"name"`
+	DisplayName string   `json:"display_name"`
+	Repo        string   `json:"repo,omitempty"`
+	Homepage    string   `json:"homepage,omitempty"`
+	Kind        string   `json:"kind,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+}
+
+// outputSourcesJSON prints the available sources, sorted by name, with their
+// metadata and configured aliases.
+func outputSourcesJSON(cfg *Config) {
+	var infos []sourceInfo
+	for _, src := range sources {
+		info := sourceInfo{
+			Name:        src.Name,
+			DisplayName: src.DisplayName,
+			Repo:        src.Repo,
+			Homepage:    src.Homepage,
+			Kind:        src.Kind,
+		}
+		for alias, target := range cfg.Aliases {
+			if target == src.Name {
+				info.Aliases = append(info.Aliases, alias)
+			}
+		}
+		sort.Strings(info.Aliases)
+		infos = append(infos, info)
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(infos)
+}
+
+// printDryRun describes what fetching src would do: the parser it applies
+// and the URLs it requests.
+func printDryRun(src Source, parserName, pattern string) {
+	parser := src.Parser
+	switch {
+	case pattern != "":
+		parser = fmt.Sprintf("markdown (pattern %q)", pattern)
+	case parser == "" && parserName != "":
+		parser = parserName
+	case parser == "":
+		parser = "detected from content"
+	}
+
+	fmt.Printf("%s (%s)\n", src.Name, src.DisplayName)
+	fmt.Printf("  parser: %s\n", parser)
+	if src.RequestURLs != nil {
+		for _, url := range src.RequestURLs() {
+			fmt.Printf("  fetch:  %s\n", url)
+		}
+	}
+}
+
+// fatalf reports a fatal error on stderr and exits with status 1. When -json
+// is set the error is written as {"error": "..."} so JSON pipelines can parse
+// it.
+func fatalf(format string, a ...any) {
+	msg := fmt.Sprintf(format, a...)
+	if jsonErrors {
+		json.NewEncoder(os.Stderr).Encode(map[string]string{"error": msg})
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", msg)
+	}
+	os.Exit(1)
+}
+
+// parseGlobalFlags applies flags that affect every command and returns the
+// remaining arguments.
+func parseGlobalFlags(args []string) ([]string, error) {
+	if host := os.Getenv("AIC_GITHUB_HOST"); host != "" {
+		githubHost = normalizeHost(host)
+	}
+	userAgent = "aic/" + version
+	if ua := os.Getenv("AIC_USER_AGENT"); ua != "" {
+		userAgent = ua
+	}
+
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-github-host", "--github-host":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires a host", args[i])
+			}
+			githubHost = normalizeHost(args[i+1])
+			i++
+		case "-commit", "--commit":
+			includeCommits = true
+		case "-include-raw", "--include-raw":
+			includeRaw = true
+		case "-backfill-dates", "--backfill-dates":
+			backfillDates = true
+		case "-unreleased", "--unreleased":
+			includeUnreleased = true
+		case "-keep-tag", "--keep-tag":
+			keepTag = true
+		case "-user-agent", "--user-agent":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires a value", args[i])
+			}
+			userAgent = args[i+1]
+			i++
+		case "-quiet", "--quiet", "-q":
+			quiet = true
+		case "-no-cache", "--no-cache":
+			noCache = true
+		case "-stale-if-error", "--stale-if-error":
+			staleIfError = true
+		case "-best-effort", "--best-effort":
+			bestEffort = true
+		case "-max-pages", "--max-pages":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires a number", args[i])
+			}
+			n, err := strconv.Atoi(args[i+1])
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("%s expects a positive number, got '%s'", args[i], args[i+1])
+			}
+			maxPages = n
+			i++
+		case "-cache-stats", "--cache-stats":
+			showCacheStats = true
+		case "-print-cache-path", "--print-cache-path":
+			printCachePath = true
+		case "-date-from", "--date-from":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires a value", args[i])
+			}
+			if args[i+1] != "published" && args[i+1] != "name" {
+				return nil, fmt.Errorf("invalid %s '%s' (expected published or name)", args[i], args[i+1])
+			}
+			releaseDateFrom = args[i+1]
+			i++
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, nil
+}
+
+func normalizeHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	return strings.TrimSuffix(host, "/")
+}
+
+// githubAPIURL returns the REST API URL for path on the configured GitHub host.
+func githubAPIURL(path string) string {
+	if githubHost == "github.com" {
+		return "https://api.github.com" + path
+	}
+	return "https://" + githubHost + "/api/v3" + path
+}
+
+// githubRawURL returns the URL of a raw file in a repository on the configured
+// GitHub host.
+func githubRawURL(owner, repo, ref, path string) string {
+	if githubHost == "github.com" {
+		return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s/%s", owner, repo, ref, path)
+	}
+	return fmt.Sprintf("https://%s/%s/%s/raw/%s/%s", githubHost, owner, repo, ref, path)
+}
+
+// suggestSource returns the known source closest to name, or "" if none is
+// within a couple of edits.
+func suggestSource(name string) string {
+	const maxDistance = 2
+
+	best := ""
+	bestDistance := maxDistance + 1
+	for candidate := range sources {
+		d := levenshtein(name, candidate)
+		if d < bestDistance || (d == bestDistance && candidate < best) {
+			best = candidate
+			bestDistance = d
+		}
+	}
+	if bestDistance > maxDistance {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// runCurrentCheck reports whether installed is older than latest and returns
+// the exit code: 0 when up to date, 1 when outdated.
+func runCurrentCheck(installed, latest string, jsonOutput bool) int {
+	outdated := compareVersions(installed, latest) < 0
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Installed string `json:"installed"`
+			Latest    string `json:"latest"`
+			Outdated  bool   `json:"outdated"`
+		}{installed, latest, outdated})
+	} else if outdated {
+		fmt.Printf("outdated (latest %s)\n", latest)
+	} else {
+		fmt.Println("up to date")
+	}
+
+	if outdated {
+		return 1
+	}
+	return 0
+}
+
+// compareVersions compares two semver-style versions, returning -1, 0 or 1.
+// A leading "v" is ignored and a pre-release sorts before its release.
+func compareVersions(a, b string) int {
+	a, preA, _ := strings.Cut(strings.TrimPrefix(a, "v"), "-")
+	b, preB, _ := strings.Cut(strings.TrimPrefix(b, "v"), "-")
+
+	if c := compareDotted(a, b); c != 0 {
+		return c
+	}
+
+	switch {
+	case preA == preB:
+		return 0
+	case preA == "":
+		return 1
+	case preB == "":
+		return -1
+	}
+	return compareDotted(preA, preB)
+}
+
+// compareDotted compares dot-separated identifiers, numerically where both
+// parts are numbers and lexically otherwise. Missing parts count as "0".
+func compareDotted(a, b string) int {
+	partsA := strings.Split(a, ".")
+	partsB := strings.Split(b, ".")
+
+	for i := 0; i < max(len(partsA), len(partsB)); i++ {
+		pa, pb := "0", "0"
+		if i < len(partsA) {
+			pa = partsA[i]
+		}
+		if i < len(partsB) {
+			pb = partsB[i]
+		}
+
+		na, errA := strconv.Atoi(pa)
+		nb, errB := strconv.Atoi(pb)
+		if errA == nil && errB == nil {
+			if na != nb {
+				return cmp.Compare(na, nb)
+			}
+			continue
+		}
+		if pa != pb {
+			return strings.Compare(pa, pb)
+		}
+	}
+	return 0
+}
+
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "aic - AI Coding Agent Changelog Viewer\n\n")
+	fmt.Fprintf(os.Stderr, "Usage: aic <source>... [flags]\n")
+	fmt.Fprintf(os.Stderr, "       aic latest [flags]\n")
+	fmt.Fprintf(os.Stderr, "       aic all [flags]\n")
+	fmt.Fprintf(os.Stderr, "       aic compare [-since 7d] [flags]\n")
+	fmt.Fprintf(os.Stderr, "       aic digest [-weekly|-monthly] [-since d] [flags]\n")
+	fmt.Fprintf(os.Stderr, "       aic watch [-interval 10m] [-json-stream] [flags]\n")
+	fmt.Fprintf(os.Stderr, "       aic diff <source> [<from>] <to> [-stat] [-json] [-no-separator] [-separator-width <n>]\n")
+	fmt.Fprintf(os.Stderr, "       aic file <path|url>... [flags]\n")
+	fmt.Fprintf(os.Stderr, "       aic gist <gist-id> [flags]\n\n")
+	fmt.Fprintf(os.Stderr, "Sources:\n")
+	fmt.Fprintf(os.Stderr, "  claude      Claude Code (Anthropic)\n")
+	fmt.Fprintf(os.Stderr, "  codex       Codex CLI (OpenAI)\n")
+	fmt.Fprintf(os.Stderr, "  opencode    OpenCode (SST)\n")
+	fmt.Fprintf(os.Stderr, "  gemini      Gemini CLI (Google)\n")
+	fmt.Fprintf(os.Stderr, "  copilot     Copilot CLI (GitHub)\n")
+	fmt.Fprintf(os.Stderr, "  goose       Goose (Block)\n")
+	fmt.Fprintf(os.Stderr, "  roo         Roo Code (Roo Code Inc.)\n")
+	fmt.Fprintf(os.Stderr, "  amp         Amp (Sourcegraph)\n\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n")
+	fmt.Fprintf(os.Stderr, "  latest             Show releases from all sources in last 24h\n")
+	fmt.Fprintf(os.Stderr, "  all                Show the newest entry of every source\n")
+	fmt.Fprintf(os.Stderr, "  compare [-since d] List each source's releases since d (default 7d)\n")
+	fmt.Fprintf(os.Stderr, "  digest             Markdown digest of this week's releases (-monthly: month's)\n")
+	fmt.Fprintf(os.Stderr, "  watch              Poll all sources and report new releases as they appear\n")
+	fmt.Fprintf(os.Stderr, "  diff <src> <a> <b> Show changes added and removed between two versions\n")
+	fmt.Fprintf(os.Stderr, "  file <path|url>    Read a changelog from a file or URL\n")
+	fmt.Fprintf(os.Stderr, "  gist <id>          Read a changelog from a GitHub Gist\n")
+	fmt.Fprintf(os.Stderr, "  cache clear|info   Remove or describe cached entries\n")
+	fmt.Fprintf(os.Stderr, "  tui                Browse sources and versions interactively\n")
+	fmt.Fprintf(os.Stderr, "  serve [-addr a]    Serve changelogs as JSON over HTTP (default :8080)\n")
+	fmt.Fprintf(os.Stderr, "  completions <sh>   Print completion script (bash, zsh, fish)\n\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	fmt.Fprintf(os.Stderr, "  -json              Output as JSON\n")
+	fmt.Fprintf(os.Stderr, "  -md                Output as markdown\n")
+	fmt.Fprintf(os.Stderr, "  -shell             Print each change on its own line, shell-quoted\n")
+	fmt.Fprintf(os.Stderr, "  -template <tmpl>   Format each entry with a Go template\n")
+	fmt.Fprintf(os.Stderr, "  -template-file <f> Format each entry with the Go template in file f\n")
+	fmt.Fprintf(os.Stderr, "  -front-matter      Prepend YAML front-matter to -md output\n")
+	fmt.Fprintf(os.Stderr, "  -md-no-date        Leave the date out of the -md heading\n")
+	fmt.Fprintf(os.Stderr, "  -md-no-sections    Print -md changes as one list without section headings\n")
+	fmt.Fprintf(os.Stderr, "  -strip-pr-links    Drop bare pull request and compare URLs from -md changes\n")
+	fmt.Fprintf(os.Stderr, "  -output-dir <dir>  With -md, write each source to <dir>/<source>.md\n")
+	fmt.Fprintf(os.Stderr, "  -list              List all versions\n")
+	fmt.Fprintf(os.Stderr, "  -since <d>         Only consider entries released since d (date or age)\n")
+	fmt.Fprintf(os.Stderr, "  -until <date>      Only consider entries released on or before date\n")
+	fmt.Fprintf(os.Stderr, "  -max-age <age>     Warn that a source is STALE if it has not released within age\n")
+	fmt.Fprintf(os.Stderr, "  -all               Show every entry (a JSON array with -json)\n")
+	fmt.Fprintf(os.Stderr, "  -n <count>         Show the count newest entries in full\n")
+	fmt.Fprintf(os.Stderr, "  -toc               Show only section names with change counts\n")
+	fmt.Fprintf(os.Stderr, "  -category-counts   Print change counts per section as a JSON object\n")
+	fmt.Fprintf(os.Stderr, "  -head <n>          Show at most n changes per entry\n")
+	fmt.Fprintf(os.Stderr, "  -wrap <cols>       Wrap plain text at cols (0 = off, default terminal width)\n")
+	fmt.Fprintf(os.Stderr, "  -no-emoji          Strip leading emoji from plain text\n")
+	fmt.Fprintf(os.Stderr, "  -no-separator      Leave out the dashed line under plain text headers\n")
+	fmt.Fprintf(os.Stderr, "  -separator-width <n>  Length of that line (default: terminal width up to 80, else 40)\n")
+	fmt.Fprintf(os.Stderr, "  -prefix            Prefix plain text lines with the source name, e.g. [claude]\n")
+	fmt.Fprintf(os.Stderr, "  -theme <name>      Color theme for plain text: dark (default), light, mono\n")
+	fmt.Fprintf(os.Stderr, "  -max-change-len <n>  Truncate changes to n characters (not in JSON)\n")
+	fmt.Fprintf(os.Stderr, "  -no-ungrouped      Hide changes outside any section\n")
+	fmt.Fprintf(os.Stderr, "  -only-ungrouped    Show only changes outside any section\n")
+	fmt.Fprintf(os.Stderr, "  -normalize-sections  Rename sections to canonical categories (Added, Fixed, ...)\n")
+	fmt.Fprintf(os.Stderr, "  -no-merge-sections   Keep repeated sections with the same name separate\n")
+	fmt.Fprintf(os.Stderr, "  -sort-changes       Sort changes alphabetically within each section\n")
+	fmt.Fprintf(os.Stderr, "  -flatten-single-section  Drop the header of a release's only section\n")
+	fmt.Fprintf(os.Stderr, "  -flat              List all changes without section headers\n")
+	fmt.Fprintf(os.Stderr, "  -annotate-version  Prefix each change with its version, e.g. [1.2.3]\n")
+	fmt.Fprintf(os.Stderr, "  -json-minify-changes  Strip markdown from change text in JSON (lossy)\n")
+	fmt.Fprintf(os.Stderr, "  -uniform-sections  In JSON, move ungrouped changes into an \"Other\" section\n")
+	fmt.Fprintf(os.Stderr, "  -version <ver>     Get specific version\n")
+	fmt.Fprintf(os.Stderr, "  -version-latest    Print only the newest version\n")
+	fmt.Fprintf(os.Stderr, "  -as-of <date>      Only consider entries released on or before date\n")
+	fmt.Fprintf(os.Stderr, "  -current <ver>     Check an installed version against the latest\n")
+	fmt.Fprintf(os.Stderr, "  -since-version <v> Show every release newer than v, i.e. what upgrading brings\n")
+	fmt.Fprintf(os.Stderr, "  -if-changed        Print nothing unless the newest version changed since the last run\n")
+	fmt.Fprintf(os.Stderr, "  -github-host <h>   Use a GitHub Enterprise host\n")
+	fmt.Fprintf(os.Stderr, "  -user-agent <ua>   User-Agent for all requests (default aic/<version>)\n")
+	fmt.Fprintf(os.Stderr, "  -date-from <src>   Release date source: published (default), name\n")
+	fmt.Fprintf(os.Stderr, "  -commit            Include release commit SHAs (GitHub sources)\n")
+	fmt.Fprintf(os.Stderr, "  -include-raw       Include each release's unparsed body (GitHub sources)\n")
+	fmt.Fprintf(os.Stderr, "  -backfill-dates    Date markdown entries from their tags' commits (claude)\n")
+	fmt.Fprintf(os.Stderr, "  -unreleased        Include a Keep a Changelog file's Unreleased section\n")
+	fmt.Fprintf(os.Stderr, "  -keep-tag          Use GitHub release tags as versions unchanged\n")
+	fmt.Fprintf(os.Stderr, "  -parser <name>     Parser for file: keepachangelog, github, markdown\n")
+	fmt.Fprintf(os.Stderr, "  -pattern <regex>   Version heading regex for file (group 1 = version)\n")
+	fmt.Fprintf(os.Stderr, "  -file <name>       Gist file to read (default: first markdown file)\n")
+	fmt.Fprintf(os.Stderr, "  -summary           Print totals after latest output\n")
+	fmt.Fprintf(os.Stderr, "  -jsonl-file <path> Append new latest entries to an NDJSON file\n")
+	fmt.Fprintf(os.Stderr, "  -dedupe-across     Collapse changes repeated across latest entries\n")
+	fmt.Fprintf(os.Stderr, "  -strict            Exit non-zero if any latest source fails\n")
+	fmt.Fprintf(os.Stderr, "  -sources <a,b>     Only fetch these sources in latest or all\n")
+	fmt.Fprintf(os.Stderr, "  -exclude-sources <a,b>  Skip these sources in latest or all\n")
+	fmt.Fprintf(os.Stderr, "  -quiet             Suppress progress output\n")
+	fmt.Fprintf(os.Stderr, "  -no-cache          Don't use the parsed-entry cache\n")
+	fmt.Fprintf(os.Stderr, "  -cache-stats       Print cache hits and misses to stderr\n")
+	fmt.Fprintf(os.Stderr, "  -print-cache-path  Print the cache directory and exit\n")
+	fmt.Fprintf(os.Stderr, "  -stale-if-error    Use the last cached entries when a fetch fails\n")
+	fmt.Fprintf(os.Stderr, "  -max-pages <n>     Pages of GitHub releases to fetch (default 1)\n")
+	fmt.Fprintf(os.Stderr, "  -best-effort       Use the pages fetched so far when a later page fails\n")
+	fmt.Fprintf(os.Stderr, "  -dry-run           Print the URLs and parser a fetch would use, then exit\n")
+	fmt.Fprintf(os.Stderr, "  -open              Open the entry's release page in a browser\n")
+	fmt.Fprintf(os.Stderr, "  -v, --version      Show aic version\n")
+	fmt.Fprintf(os.Stderr, "  -h, --help         Show this help\n\n")
+	fmt.Fprintf(os.Stderr, "Examples:\n")
+	fmt.Fprintf(os.Stderr, "  aic claude                    # Latest Claude Code entry\n")
+	fmt.Fprintf(os.Stderr, "  aic codex -json               # Latest Codex entry as JSON\n")
+	fmt.Fprintf(os.Stderr, "  aic opencode -list            # List OpenCode versions\n")
+	fmt.Fprintf(os.Stderr, "  aic gemini -version 0.21.0    # Specific Gemini version\n")
+	fmt.Fprintf(os.Stderr, "  aic claude codex -md          # Several sources at once\n")
+	fmt.Fprintf(os.Stderr, "  aic latest                    # All releases in last 24h\n\n")
+	fmt.Fprintf(os.Stderr, "Set AIC_DEFAULT_SOURCE to show a source when no source is given.\n")
+}
+
+// selectSources returns the sources named in include, or all sources when
+// include is empty, minus those named in exclude. Unknown names are an error.
+func selectSources(include, exclude []string) (map[string]Source, error) {
+	selected := map[string]Source{}
+	if len(include) == 0 {
+		for name, src := range sources {
+			selected[name] = src
+		}
+	}
+	for _, name := range include {
+		src, ok := sources[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown source '%s'", name)
+		}
+		selected[name] = src
+	}
+
+	for _, name := range exclude {
+		if _, ok := sources[name]; !ok {
+			return nil, fmt.Errorf("unknown source '%s'", name)
+		}
+		delete(selected, name)
+	}
+	return selected, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// latestOptions holds the flags accepted by the latest command.
+type latestOptions struct {
+	outputOptions
+	jsonOutput   bool
+	summary      bool
+	jsonlFile    string
+	dedupeAcross bool
+	strict       bool
+
+	sources        []string
+	excludeSources []string
+}
+
+// latestSummary totals what the latest command found.
+type latestSummary struct {
+	Sources  int `json:"sources"`
+	Releases int `json:"releases"`
+	Changes  int `json:"changes"`
+}
+
+func runLatestCommand(opts latestOptions) {
+	cutoff := time.Now().Add(-24 * time.Hour)
+
+	selected, err := selectSources(opts.sources, opts.excludeSources)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	prog := newProgress(len(selected))
+	prog.start("Fetching")
+
+	var failed []string
+	recentEntries := fetchLatest(selected, cutoff, func(display string, err error) {
+		prog.step(display)
+		if err != nil {
+			prog.clear()
+			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch %s: %v\n", display, err)
+			failed = append(failed, display)
+		}
+	})
+
+	prog.clear()
+	reportCacheStats()
+
+	if opts.strict && len(failed) > 0 {
+		sort.Strings(failed)
+		fatalf("failed to fetch %s", strings.Join(failed, ", "))
+	}
+
+	if opts.jsonlFile != "" {
+		if _, err := appendJSONLines(opts.jsonlFile, recentEntries); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to update %s: %v\n", opts.jsonlFile, err)
+		}
+	}
+
+	summary := latestSummary{
+		Sources:  len(selected),
+		Releases: len(recentEntries),
+	}
+	for _, entry := range recentEntries {
+		summary.Changes += countChanges(&entry)
+	}
+
+	if opts.dedupeAcross {
+		recentEntries = dedupeAcross(recentEntries)
+	}
+
+	for i := range recentEntries {
+		recentEntries[i] = applyOutputOptions(recentEntries[i], opts.outputOptions)
+	}
+
+	if opts.jsonOutput && opts.summary {
+		if recentEntries == nil {
+			recentEntries = []ChangelogEntry{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(struct {
+			Entries []ChangelogEntry `json:"entries"`
+			Meta    latestSummary    `json:"meta"`
+		}{recentEntries, summary})
+		return
+	}
+
+	if len(recentEntries) == 0 {
+		fmt.Println("No releases in the last 24 hours.")
+	} else if opts.jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(recentEntries)
+	} else {
+		// Entries carry display names; -prefix wants source names.
+		names := map[string]string{}
+		for name, src := range selected {
+			names[src.DisplayName] = name
+		}
+		for i, entry := range recentEntries {
+			if i > 0 {
+				fmt.Println()
+			}
+			outputPlainText(names[entry.Source], entry.Source, &entry, opts.outputOptions)
+		}
+	}
+
+	if opts.summary {
+		fmt.Printf("\n%d sources, %d releases, %d changes in the last 24h\n", summary.Sources, summary.Releases, summary.Changes)
+	}
+}
+
+// fetchLatest fetches selected concurrently and returns the newest entry of
+// each source released after cutoff, newest first. done is called, one call
+// at a time, as each fetch finishes.
+func fetchLatest(selected map[string]Source, cutoff time.Time, done func(display string, err error)) []ChangelogEntry {
+	type result struct {
+		display string
+		entry   *ChangelogEntry
+		err     error
+	}
+
+	results := make(chan result, len(selected))
+	var wg sync.WaitGroup
+
+	for _, src := range selected {
+		wg.Add(1)
+		go func(src Source) {
+			defer wg.Done()
+			entries, err := fetchSource(src)
+			if err != nil {
+				results <- result{display: src.DisplayName, err: err}
+				return
+			}
+			r := result{display: src.DisplayName}
+			if len(entries) > 0 {
+				entry := entries[0]
+				entry.Source = src.DisplayName
+				r.entry = &entry
+			}
+			results <- r
+		}(src)
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var recentEntries []ChangelogEntry
+	for r := range results {
+		done(r.display, r.err)
+		if r.err == nil && r.entry != nil && !r.entry.ReleasedAt.IsZero() && r.entry.ReleasedAt.After(cutoff) {
+			recentEntries = append(recentEntries, *r.entry)
+		}
+	}
+
+	// Sort by release date descending
+	sort.Slice(recentEntries, func(i, j int) bool {
+		return recentEntries[i].ReleasedAt.After(recentEntries[j].ReleasedAt)
+	})
+	return recentEntries
+}
+
+// dedupeAcross collapses change text repeated across entries, such as a
+// shared dependency bump, into its first occurrence. The kept change is
+// annotated with the other sources and versions that listed it.
+func dedupeAcross(entries []ChangelogEntry) []ChangelogEntry {
+	type shared struct {
+		entry int
+		text  *string
+		also  []string
+	}
+	seen := map[string]*shared{}
+
+	result := make([]ChangelogEntry, len(entries))
+	for i, entry := range entries {
+		label := strings.TrimSpace(entry.Source + " " + entry.Version)
+		keep := func(changes []string) []string {
+			kept := make([]string, 0, len(changes))
+			for _, change := range changes {
+				key := strings.TrimSpace(change)
+				if sh, ok := seen[key]; ok && sh.entry != i {
+					sh.also = append(sh.also, label)
+					continue
+				}
+				kept = append(kept, change)
+			}
+			return kept
+		}
+		register := func(changes []string) {
+			for j := range changes {
+				key := strings.TrimSpace(changes[j])
+				if _, ok := seen[key]; !ok {
+					seen[key] = &shared{entry: i, text: &changes[j]}
+				}
+			}
+		}
+
+		var sections []Section
+		for _, section := range entry.Sections {
+			section.Changes = keep(section.Changes)
+			register(section.Changes)
+			if len(section.Changes) > 0 {
+				sections = append(sections, section)
+			}
+		}
+		entry.Sections = sections
+		entry.Changes = keep(entry.Changes)
+		register(entry.Changes)
+		result[i] = entry
+	}
+
+	for _, sh := range seen {
+		if len(sh.also) > 0 {
+			*sh.text += " (also in " + strings.Join(sh.also, ", ") + ")"
+		}
+	}
+	return result
+}
+
+// appendJSONLines appends entries to the NDJSON file at path, one object per
+// line, skipping any whose source and version are already recorded there. It
+// returns the number of entries written.
+func appendJSONLines(path string, entries []ChangelogEntry) (int, error) {
+	seen := map[string]bool{}
+	key := func(source, version string) string { return source + "\x00" + version }
+
+	if data, err := os.ReadFile(path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			var existing struct {
+				Source  string `json:"source"`
+				Version string `json:"version"`
+			}
+			if json.Unmarshal([]byte(line), &existing) == nil {
+				seen[key(existing.Source, existing.Version)] = true
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	written := 0
+	for _, entry := range entries {
+		k := key(entry.Source, entry.Version)
+		if seen[k] {
+			continue
+		}
+		if err := encoder.Encode(entry); err != nil {
+			return written, err
+		}
+		seen[k] = true
+		written++
+	}
+	return written, nil
+}
+
+// countChanges returns the number of changes in entry across all sections.
+func countChanges(entry *ChangelogEntry) int {
+	n := len(entry.Changes)
+	for _, section := range entry.Sections {
+		n += len(section.Changes)
+	}
+	return n
+}
+
+// markdownVersionPattern matches "## 1.2.3" or "## 1.2.3 (2024-01-07)"
+// headings, and ranges such as "## 1.2.0 - 1.2.3" that cover several
+// versions.
+const markdownVersionPattern = `(?m)^## (\d+\.\d+\.\d+)(?:\s*[-–]\s*(?P<to>\d+\.\d+\.\d+))?(?:\s+\((?P<date>\d{4}-\d{2}-\d{2})\))?\s*$`
+
+func claudeChangelogURL() string {
+	return githubRawURL("anthropics", "claude-code", "main", "CHANGELOG.md")
+}
+
+// claudeChangelogURLs lists the URLs fetchClaudeChangelog may request. The
+// commits lookup only happens when the newest heading has no date.
+func claudeChangelogURLs() []string {
+	urls := []string{
+		claudeChangelogURL(),
+		githubFileCommitsURL("anthropics", "claude-code", "CHANGELOG.md", 20),
+	}
+	if backfillDates {
+		urls = append(urls, githubTagsURL("anthropics", "claude-code"))
+	}
+	return urls
+}
+
+func fetchClaudeChangelog() ([]ChangelogEntry, error) {
+	url := claudeChangelogURL()
+	content, err := httpGet(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return cachedParse(url, []byte(content), func() ([]ChangelogEntry, error) {
+		entries := parseMarkdownChangelogWithOptionalDate(content, markdownVersionPattern)
+
+		var lookupErr error
+		if backfillDates {
+			lookupErr = backfillTagDates("anthropics", "claude-code", entries)
+		}
+
+		if len(entries) > 0 && entries[0].ReleasedAt.IsZero() {
+			commits, err := fetchGitHubFileCommits("anthropics", "claude-code", "CHANGELOG.md", 20)
+			if err != nil {
+				lookupErr = err
+			}
+			if commitDate := commitDateForVersion(commits, entries[0].Version); !commitDate.IsZero() {
+				entries[0].ReleasedAt = commitDate
+			}
+		}
+
+		if lookupErr != nil {
+			return entries, errIncomplete
+		}
+		return entries, nil
+	})
+}
+
+// fileCommit is a commit that touched a file, as reported by the GitHub API.
+type fileCommit struct {
+	Date    time.Time
+	Message string
+}
+
+func githubFileCommitsURL(owner, repo, path string, n int) string {
+	return githubAPIURL(fmt.Sprintf("/repos/%s/%s/commits?path=%s&per_page=%d", owner, repo, path, n))
+}
+
+// fetchGitHubFileCommits returns up to n of the most recent commits touching
+// path, newest first.
+func fetchGitHubFileCommits(owner, repo, path string, n int) ([]fileCommit, error) {
+	url := githubFileCommitsURL(owner, repo, path, n)
+
+	req, err := newGitHubRequest(url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	return parseFileCommits(resp.Body)
+}
+
+// parseFileCommits decodes a GitHub commits API response.
+func parseFileCommits(r io.Reader) ([]fileCommit, error) {
+	var raw []struct {
+		Commit struct {
+			Message   string `json:"message"`
+			Committer struct {
+				Date string `json:"date"`
+			} `json:"committer"`
+		} `json:"commit"`
+	}
+
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+
+	commits := make([]fileCommit, 0, len(raw))
+	for _, c := range raw {
+		t, _ := time.Parse(time.RFC3339, c.Commit.Committer.Date)
+		commits = append(commits, fileCommit{Date: t, Message: c.Commit.Message})
+	}
+	return commits, nil
+}
+
+// commitDateForVersion picks the release date for version from commits,
+// newest first. The most recent commit whose message mentions the version
+// wins, so a later typo fix to the changelog doesn't move the date; otherwise
+// the most recent commit is used. It returns the zero time for no commits.
+func commitDateForVersion(commits []fileCommit, version string) time.Time {
+	if len(commits) == 0 {
+		return time.Time{}
+	}
+	mention := regexp.MustCompile(`(^|[^\d.])v?` + regexp.QuoteMeta(version) + `($|[^\d.]|\.[^\d])`)
+	for _, c := range commits {
+		if mention.MatchString(c.Message) {
+			return c.Date
+		}
+	}
+	return commits[0].Date
+}
+
+func githubTagsURL(owner, repo string) string {
+	return githubAPIURL(fmt.Sprintf("/repos/%s/%s/tags?per_page=100", owner, repo))
+}
+
+// fetchGitHubTagCommits returns a map of tag name to commit SHA for the most
+// recent tags of a repository, reading up to -max-pages pages of them. A
+// failure returns the tags read so far along with the error.
+func fetchGitHubTagCommits(owner, repo string) (map[string]string, error) {
+	commits := map[string]string{}
+	for page := 1; page <= maxPages; page++ {
+		hasNext, err := fetchGitHubTagCommitsPage(owner, repo, page, commits)
+		if err != nil {
+			return commits, err
+		}
+		if !hasNext {
+			break
+		}
+	}
+	return commits, nil
+}
+
+// fetchGitHubTagCommitsPage adds one page of tags to commits and reports
+// whether there is a next page.
+func fetchGitHubTagCommitsPage(owner, repo string, page int, commits map[string]string) (bool, error) {
+	url := githubTagsURL(owner, repo)
+	if page > 1 {
+		url += fmt.Sprintf("&page=%d", page)
+	}
+
+	req, err := newGitHubRequest(url)
 	if err != nil {
-		return time.Time{}
+		return false, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return time.Time{}
+		return false, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	var tags []struct {
+		Name   string `json:"name"`
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&tags); err != nil {
+		return false, err
+	}
+
+	for _, tag := range tags {
+		commits[tag.Name] = tag.Commit.SHA
+	}
+	return parsePageLinks(resp.Header.Get("Link")).hasNext, nil
+}
+
+func githubCommitURL(owner, repo, sha string) string {
+	return githubAPIURL(fmt.Sprintf("/repos/%s/%s/commits/%s", owner, repo, sha))
+}
+
+// fetchGitHubCommitDate returns the committer date of a commit.
+func fetchGitHubCommitDate(owner, repo, sha string) (time.Time, error) {
+	req, err := newGitHubRequest(githubCommitURL(owner, repo, sha))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return time.Time{}, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
 	}
 
-	var commits []struct {
+	var commit struct {
 		Commit struct {
 			Committer struct {
 				Date string `json:"date"`
 			} `json:"committer"`
 		} `json:"commit"`
 	}
+	if err := json.NewDecoder(resp.Body).Decode(&commit); err != nil {
+		return time.Time{}, err
+	}
+	t, _ := time.Parse(time.RFC3339, commit.Commit.Committer.Date)
+	return t, nil
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&commits); err != nil || len(commits) == 0 {
-		return time.Time{}
+// backfillTagDates dates the undated entries from the commits of the tags
+// matching their versions, at one request per entry. Entries without a
+// matching tag stay undated. It returns the first lookup that failed, after
+// dating every entry it could.
+func backfillTagDates(owner, repo string, entries []ChangelogEntry) error {
+	tagCommits, firstErr := fetchGitHubTagCommits(owner, repo)
+	shas := map[string]string{}
+	for tag, sha := range tagCommits {
+		shas[tag] = sha
+		shas[trimTagPrefix(tag)] = sha
 	}
 
-	t, _ := time.Parse(time.RFC3339, commits[0].Commit.Committer.Date)
-	return t
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	limit := make(chan struct{}, 8)
+	for i := range entries {
+		sha, ok := shas[entries[i].Version]
+		if !ok || !entries[i].ReleasedAt.IsZero() {
+			continue
+		}
+		wg.Add(1)
+		go func(entry *ChangelogEntry, sha string) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			date, err := fetchGitHubCommitDate(owner, repo, sha)
+			entry.ReleasedAt = date
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(&entries[i], sha)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+var commitSHARegex = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// releaseCommit returns the commit SHA for a release tag, using the tags
+// lookup or a target_commitish that is already a full SHA. It returns "" when
+// commits weren't requested or the SHA is unknown.
+func releaseCommit(tag, targetCommitish string, tagCommits map[string]string) string {
+	if tagCommits == nil {
+		return ""
+	}
+	if sha, ok := tagCommits[tag]; ok {
+		return sha
+	}
+	if commitSHARegex.MatchString(targetCommitish) {
+		return targetCommitish
+	}
+	return ""
 }
 
 func fetchCodexChangelog() ([]ChangelogEntry, error) {
@@ -331,86 +2122,676 @@ func fetchGeminiChangelog() ([]ChangelogEntry, error) {
 	return fetchGitHubReleases("google-gemini", "gemini-cli")
 }
 
+func fetchGooseChangelog() ([]ChangelogEntry, error) {
+	return fetchGitHubReleases("block", "goose")
+}
+
+func fetchRooChangelog() ([]ChangelogEntry, error) {
+	return fetchGitHubReleases("RooCodeInc", "Roo-Code")
+}
+
+func copilotChangelogURL() string {
+	return githubRawURL("github", "copilot-cli", "main", "changelog.md")
+}
+
 func fetchCopilotChangelog() ([]ChangelogEntry, error) {
-	url := "https://raw.githubusercontent.com/github/copilot-cli/main/changelog.md"
+	url := copilotChangelogURL()
 	content, err := httpGet(url)
 	if err != nil {
 		return nil, err
 	}
-	return parseMarkdownChangelogWithDate(content, `(?m)^## ([\d.]+) - (\d{4}-\d{2}-\d{2})\s*$`), nil
+	return cachedParse(url, []byte(content), func() ([]ChangelogEntry, error) {
+		return parseMarkdownChangelogWithDate(content, `(?m)^## ([\d.]+) - (\d{4}-\d{2}-\d{2})\s*$`)
+	})
 }
 
-func fetchGitHubReleases(owner, repo string) ([]ChangelogEntry, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/releases", owner, repo)
+const ampNewsURL = "https://ampcode.com/news"
 
-	req, err := http.NewRequest("GET", url, nil)
+// ampNewsLink matches a link to an Amp news post and captures its slug.
+var ampNewsLink = regexp.MustCompile(`(?is)<a\b[^>]*href="(?:https://ampcode\.com)?/news/([^"#?/]+)"[^>]*>(.*?)</a>`)
+
+func fetchAmpChangelog() ([]ChangelogEntry, error) {
+	content, err := httpGet(ampNewsURL)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Accept", "application/vnd.github+json")
-	req.Header.Set("User-Agent", "aic-changelog")
+	return cachedParse(ampNewsURL, []byte(content), func() ([]ChangelogEntry, error) {
+		return parseAmpNews(content)
+	})
+}
+
+// parseAmpNews parses the Amp news index. Amp has no version numbers, so each
+// post's slug is used as its version. Posts are read from <article> elements,
+// falling back to bare links to /news/ pages.
+func parseAmpNews(page string) ([]ChangelogEntry, error) {
+	var entries []ChangelogEntry
+	seen := map[string]bool{}
+
+	for _, article := range htmlElements(page, "article") {
+		link := ampNewsLink.FindStringSubmatch(article)
+		if link == nil || seen[link[1]] {
+			continue
+		}
+		seen[link[1]] = true
+
+		entry := ChangelogEntry{Version: link[1], ReleasedAt: htmlDate(article), URL: ampNewsURL + "/" + link[1]}
+		title := htmlText(link[2])
+		for _, tag := range []string{"h1", "h2", "h3"} {
+			if headings := htmlElements(article, tag); len(headings) > 0 {
+				title = htmlText(headings[0])
+				break
+			}
+		}
+		if title != "" {
+			entry.Changes = append(entry.Changes, title)
+		}
+
+		items := htmlElements(article, "li")
+		if len(items) == 0 {
+			items = htmlElements(article, "p")
+		}
+		for _, item := range items {
+			if text := htmlText(item); text != "" && text != title {
+				entry.Changes = append(entry.Changes, text)
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		for _, link := range ampNewsLink.FindAllStringSubmatch(page, -1) {
+			title := htmlText(link[2])
+			if seen[link[1]] || title == "" {
+				continue
+			}
+			seen[link[1]] = true
+			entries = append(entries, ChangelogEntry{
+				Version:    link[1],
+				ReleasedAt: htmlDate(link[2]),
+				Changes:    []string{title},
+				URL:        ampNewsURL + "/" + link[1],
+			})
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("amp: %w", errFormatChanged)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ReleasedAt.After(entries[j].ReleasedAt)
+	})
+	return entries, nil
+}
+
+func githubReleasesURL(owner, repo string) string {
+	return githubAPIURL(fmt.Sprintf("/repos/%s/%s/releases", owner, repo))
+}
+
+// githubReleasesPageURL returns the URL of one page of releases. The first
+// page has no page parameter.
+func githubReleasesPageURL(owner, repo string, page int) string {
+	url := githubReleasesURL(owner, repo)
+	if page > 1 {
+		url += fmt.Sprintf("?page=%d", page)
+	}
+	return url
+}
+
+// githubReleaseURLs lists the URLs fetchGitHubReleases requests for a
+// repository.
+func githubReleaseURLs(owner, repo string) func() []string {
+	return func() []string {
+		var urls []string
+		for page := 1; page <= maxPages; page++ {
+			urls = append(urls, githubReleasesPageURL(owner, repo, page))
+		}
+		if includeCommits {
+			urls = append(urls, githubTagsURL(owner, repo))
+		}
+		return urls
+	}
+}
+
+func fetchGitHubReleases(owner, repo string) ([]ChangelogEntry, error) {
+	prog := newPageProgress(showPageProgress && maxPages > 1)
+	defer prog.clear()
+
+	tags := &tagCommitLookup{owner: owner, repo: repo}
+	var entries []ChangelogEntry
+	for page := 1; page <= maxPages; page++ {
+		pageEntries, links, err := fetchGitHubReleasesPage(owner, repo, page, tags)
+		if err != nil {
+			if page == 1 {
+				return nil, err
+			}
+			return dedupeVersions(entries), fmt.Errorf("releases page %d: %w", page, err)
+		}
+		entries = append(entries, pageEntries...)
+		if !links.hasNext {
+			break
+		}
+		total := 0
+		if links.last > 0 {
+			total = min(links.last, maxPages)
+		}
+		prog.page(page, total)
+	}
+	return dedupeVersions(entries), nil
+}
+
+// dedupeVersions drops entries whose version was already seen, keeping the
+// first. A release published while pages are being fetched shifts the rest
+// down, so the last release of one page can reappear on the next.
+func dedupeVersions(entries []ChangelogEntry) []ChangelogEntry {
+	seen := map[string]bool{}
+	var kept []ChangelogEntry
+	for _, entry := range entries {
+		if seen[entry.Version] {
+			continue
+		}
+		seen[entry.Version] = true
+		kept = append(kept, entry)
+	}
+	return kept
+}
+
+// pageLinks is what a GitHub API response's Link header says about the
+// pages after it.
+type pageLinks struct {
+	hasNext bool
+	// last is the number of the last page, or 0 when it isn't listed.
+	last int
+}
+
+// githubLastPage matches the rel="last" URL of a Link header and captures its
+// page number.
+var githubLastPage = regexp.MustCompile(`<[^>]*[?&]page=(\d+)[^>]*>;\s*rel="last"`)
+
+func parsePageLinks(header string) pageLinks {
+	links := pageLinks{hasNext: strings.Contains(header, `rel="next"`)}
+	if m := githubLastPage.FindStringSubmatch(header); m != nil {
+		links.last, _ = strconv.Atoi(m[1])
+	}
+	return links
+}
+
+// fetchGitHubReleasesPage fetches one page of releases and reports what the
+// API says about the pages after it.
+func fetchGitHubReleasesPage(owner, repo string, page int, tags *tagCommitLookup) ([]ChangelogEntry, pageLinks, error) {
+	url := githubReleasesPageURL(owner, repo, page)
+
+	req, err := newGitHubRequest(url)
+	if err != nil {
+		return nil, pageLinks{}, err
+	}
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed: %w", err)
+		return nil, pageLinks{}, fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, pageLinks{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		if msg := githubErrorMessage(body); msg != "" {
+			return nil, pageLinks{}, fmt.Errorf("GitHub API error (HTTP %d): %s", resp.StatusCode, msg)
+		}
+		return nil, pageLinks{}, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	links := parsePageLinks(resp.Header.Get("Link"))
+	entries, err := cachedParse(url, body, func() ([]ChangelogEntry, error) {
+		return parseGitHubReleases(body, tags)
+	})
+	return entries, links, err
+}
+
+// trimTagPrefix turns a release tag into a version by removing the longest
+// matching entry of tagPrefixes, unless -keep-tag is set.
+func trimTagPrefix(tag string) string {
+	if keepTag {
+		return tag
+	}
+	prefixes := slices.Clone(tagPrefixes)
+	sort.SliceStable(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) > len(prefixes[j])
+	})
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(tag, prefix) {
+			return strings.TrimPrefix(tag, prefix)
+		}
+	}
+	return tag
+}
+
+// tagCommitLookup fetches a repository's tag commits for -commit on first use
+// and keeps them, so the pages of one fetch share a single tags listing and
+// pages served from the cache need none.
+type tagCommitLookup struct {
+	owner, repo string
+	done        bool
+	commits     map[string]string
+	err         error
+}
+
+func (l *tagCommitLookup) get() (map[string]string, error) {
+	if !l.done {
+		l.commits, l.err = fetchGitHubTagCommits(l.owner, l.repo)
+		l.done = true
 	}
+	return l.commits, l.err
+}
 
+// parseGitHubReleases turns a GitHub releases API response into entries. With
+// -commit, commits come from tags, and a failed tag lookup returns the
+// entries with errIncomplete.
+func parseGitHubReleases(body []byte, tags *tagCommitLookup) ([]ChangelogEntry, error) {
 	var releases []struct {
-		TagName     string `json:"tag_name"`
-		Name        string `json:"name"`
-		Body        string `json:"body"`
-		PublishedAt string `json:"published_at"`
+		TagName         string `json:"tag_name"`
+		TargetCommitish string `json:"target_commitish"`
+		Name            string `json:"name"`
+		HTMLURL         string `json:"html_url"`
+		Body            string `json:"body"`
+		PublishedAt     string `json:"published_at"`
+		Assets          []struct {
+			Name               string `json:"name"`
+			BrowserDownloadURL string `json:"browser_download_url"`
+		} `json:"assets"`
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+	if err := json.Unmarshal(body, &releases); err != nil {
+		if msg := githubErrorMessage(body); msg != "" {
+			return nil, fmt.Errorf("GitHub API error: %s", msg)
+		}
 		return nil, fmt.Errorf("failed to parse releases: %w", err)
 	}
 
+	var tagCommits map[string]string
+	var lookupErr error
+	if includeCommits {
+		tagCommits, lookupErr = tags.get()
+	}
+
 	var entries []ChangelogEntry
 	for _, rel := range releases {
-		ver := rel.TagName
-		ver = strings.TrimPrefix(ver, "v")
-		ver = strings.TrimPrefix(ver, "rust-v")
+		ver := trimTagPrefix(rel.TagName)
 
 		sections, ungroupedChanges := parseReleaseBody(rel.Body)
 
 		releasedAt, _ := time.Parse(time.RFC3339, rel.PublishedAt)
+		if releaseDateFrom == "name" {
+			if named := parseReleaseNameDate(rel.Name, rel.Body); !named.IsZero() {
+				releasedAt = named
+			}
+		}
+
+		var assets []Asset
+		for _, a := range rel.Assets {
+			assets = append(assets, Asset{Name: a.Name, DownloadURL: a.BrowserDownloadURL})
+		}
+
+		var rawBody string
+		if includeRaw {
+			rawBody = rel.Body
+		}
 
 		entries = append(entries, ChangelogEntry{
 			Version:    ver,
 			ReleasedAt: releasedAt,
 			Sections:   sections,
 			Changes:    ungroupedChanges,
+			Assets:     assets,
+			Commit:     releaseCommit(rel.TagName, rel.TargetCommitish, tagCommits),
+			RawBody:    rawBody,
+			URL:        rel.HTMLURL,
+		})
+	}
+
+	if lookupErr != nil {
+		return entries, errIncomplete
+	}
+	return entries, nil
+}
+
+// markdownSource returns a source reading the markdown changelogs at urls,
+// as a config file's markdown-raw sources do.
+func markdownSource(name, displayName string, urls []string, parser, pattern string) Source {
+	src := Source{
+		Name:        name,
+		DisplayName: displayName,
+		FetchFunc: func() ([]ChangelogEntry, error) {
+			return fetchMarkdownChangelogs(urls, parser, pattern)
+		},
+		Parser:      parser,
+		RequestURLs: func() []string { return urls },
+		URLs:        urls,
+		Kind:        "markdown-raw",
+		Changelog:   urls[0],
+	}
+	if pattern != "" {
+		src.Parser = fmt.Sprintf("markdown (pattern %q)", pattern)
+	}
+	return src
+}
+
+// fetchMarkdownChangelogs reads the changelogs at locations in order and
+// joins their entries, keeping the first entry of a version found in
+// several. A failure after the first location returns the entries read so
+// far along with the error.
+func fetchMarkdownChangelogs(locations []string, parser, pattern string) ([]ChangelogEntry, error) {
+	var entries []ChangelogEntry
+	for i, location := range locations {
+		more, err := fetchFileChangelog(location, parser, pattern)
+		if err != nil {
+			if i == 0 {
+				return nil, err
+			}
+			return dedupeVersions(entries), fmt.Errorf("%s: %w", location, err)
+		}
+		entries = append(entries, more...)
+	}
+	if len(locations) == 1 {
+		return entries, nil
+	}
+	return dedupeVersions(entries), nil
+}
+
+// fetchFileChangelog reads a changelog from a local path or an http(s) URL
+// and parses it with parseChangelogContent.
+func fetchFileChangelog(location, parser, pattern string) ([]ChangelogEntry, error) {
+	var content string
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		body, err := httpGet(location)
+		if err != nil {
+			return nil, err
+		}
+		content = body
+	} else {
+		data, err := os.ReadFile(location)
+		if err != nil {
+			return nil, err
+		}
+		content = cleanText(data)
+	}
+
+	return parseChangelogContent(content, parser, pattern)
+}
+
+// parseChangelogContent parses a changelog document. A non-empty pattern
+// selects the generic heading parser; otherwise the named parser is used, or
+// the format is detected when parser is empty.
+func parseChangelogContent(content, parser, pattern string) ([]ChangelogEntry, error) {
+	if pattern != "" {
+		return parseMarkdownChangelog(content, pattern)
+	}
+
+	if parser == "" {
+		parser = detectFormat(content)
+	}
+
+	switch parser {
+	case "keepachangelog":
+		return parseKeepAChangelog(content), nil
+	case "github":
+		return parseSectionedChangelog(content, githubStyleHeading), nil
+	case "markdown":
+		return parseMarkdownChangelogWithOptionalDate(content, markdownVersionPattern), nil
+	}
+	return nil, fmt.Errorf("unknown parser '%s' (expected keepachangelog, github or markdown)", parser)
+}
+
+func gistURL(id string) string {
+	return githubAPIURL("/gists/" + id)
+}
+
+// fetchGistChangelog fetches a gist through the GitHub API and parses one of
+// its files: the one named fileName, or else the first markdown file.
+func fetchGistChangelog(id, fileName, parser, pattern string) ([]ChangelogEntry, error) {
+	url := gistURL(id)
+
+	req, err := newGitHubRequest(url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("HTTP request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if msg := githubErrorMessage(body); msg != "" {
+			return nil, fmt.Errorf("GitHub API error (HTTP %d): %s", resp.StatusCode, msg)
+		}
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	}
+
+	var gist struct {
+		Files map[string]struct {
+			Filename  string `json:"filename"`
+			Language  string `json:"language"`
+			Content   string `json:"content"`
+			Truncated bool   `json:"truncated"`
+			RawURL    string `json:"raw_url"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(body, &gist); err != nil {
+		return nil, fmt.Errorf("failed to parse gist: %w", err)
+	}
+
+	names := make([]string, 0, len(gist.Files))
+	for name := range gist.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	chosen := ""
+	for _, name := range names {
+		file := gist.Files[name]
+		isMarkdown := file.Language == "Markdown" || strings.HasSuffix(name, ".md") || strings.HasSuffix(name, ".markdown")
+		if (fileName != "" && name == fileName) || (fileName == "" && isMarkdown) {
+			chosen = name
+			break
+		}
+	}
+	if chosen == "" {
+		if fileName != "" {
+			return nil, fmt.Errorf("gist %s has no file named '%s'", id, fileName)
+		}
+		return nil, fmt.Errorf("gist %s has no markdown file", id)
+	}
+
+	file := gist.Files[chosen]
+	content := cleanText([]byte(file.Content))
+	if file.Truncated {
+		content, err = httpGet(file.RawURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return parseChangelogContent(content, parser, pattern)
+}
+
+// keepAChangelogHeading matches "## [1.2.3] - 2024-01-01" version headings.
+var keepAChangelogHeading = regexp.MustCompile(`(?m)^## \[([^\]]+)\](?:\s+[-–]\s+(\d{4}-\d{2}-\d{2}))?(?:\s+\[YANKED\])?[ \t]*$`)
+
+// githubStyleHeading matches headings written by release tooling common on
+// GitHub, such as "## [1.2.3](https://...) (2024-01-01)" or "# v1.2.3".
+var githubStyleHeading = regexp.MustCompile(`(?m)^#{1,2} \[?v?(\d+\.\d+\.\d+[^\]\s]*)\]?(?:\([^)]*\))?(?:\s+\((\d{4}-\d{2}-\d{2})\))?[ \t]*$`)
+
+// detectFormat guesses which parser suits content: "keepachangelog",
+// "markdown" for plain "## 1.2.3" headings, or "github". It falls back to
+// "markdown" when nothing matches.
+func detectFormat(content string) string {
+	markdownHeading := regexp.MustCompile(markdownVersionPattern)
+
+	switch {
+	case keepAChangelogHeading.MatchString(content):
+		return "keepachangelog"
+	case markdownHeading.MatchString(content):
+		return "markdown"
+	case githubStyleHeading.MatchString(content):
+		return "github"
+	}
+	return "markdown"
+}
+
+// parseKeepAChangelog parses a changelog following the Keep a Changelog
+// format, turning "### Added"-style subsections into sections. The
+// Unreleased section is skipped unless -unreleased is set.
+func parseKeepAChangelog(content string) []ChangelogEntry {
+	var entries []ChangelogEntry
+	for _, entry := range parseSectionedChangelog(content, keepAChangelogHeading) {
+		if strings.EqualFold(entry.Version, "Unreleased") {
+			if !includeUnreleased {
+				continue
+			}
+			entry.Version = "Unreleased"
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// parseSectionedChangelog splits content on heading, whose first group is the
+// version and optional second group a YYYY-MM-DD date, and parses each body
+// with parseReleaseBody so subsections become sections.
+func parseSectionedChangelog(content string, heading *regexp.Regexp) []ChangelogEntry {
+	var entries []ChangelogEntry
+
+	matches := heading.FindAllStringSubmatchIndex(content, -1)
+	for i, match := range matches {
+		ver := content[match[2]:match[3]]
+
+		var releasedAt time.Time
+		if len(match) > 5 && match[4] >= 0 {
+			releasedAt, _ = time.Parse("2006-01-02", content[match[4]:match[5]])
+		}
+
+		contentEnd := len(content)
+		if i+1 < len(matches) {
+			contentEnd = matches[i+1][0]
+		}
+
+		sections, changes := parseReleaseBody(content[match[1]:contentEnd])
+
+		entries = append(entries, ChangelogEntry{
+			Version:    ver,
+			ReleasedAt: releasedAt,
+			Sections:   sections,
+			Changes:    changes,
 		})
 	}
 
-	return entries, nil
+	return entries
+}
+
+// githubErrorMessage returns the message from a GitHub API error body such as
+// {"message": "API rate limit exceeded"}, or "" if body isn't one.
+func githubErrorMessage(body []byte) string {
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		return ""
+	}
+	return apiErr.Message
+}
+
+var releaseNameDateRegex = regexp.MustCompile(`\b(\d{4}-\d{2}-\d{2})\b`)
+
+// parseReleaseNameDate returns the first YYYY-MM-DD date found in a release
+// name such as "v1.2.3 (2024-05-01)", falling back to the first line of the
+// body. It returns the zero time when neither has a date.
+func parseReleaseNameDate(name, body string) time.Time {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(body), "\n")
+	for _, text := range []string{name, firstLine} {
+		if match := releaseNameDateRegex.FindStringSubmatch(text); match != nil {
+			if t, err := time.Parse("2006-01-02", match[1]); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
 }
 
+// contributorCredit matches bullets that only credit contributors, such as
+// "@user made their first contribution in https://..." or "@a, @b and @c",
+// as opposed to notes that merely start with a mention.
+var contributorCredit = regexp.MustCompile(`^(?:@[\w-]+(?:\[bot\])?(?:,?\s+and\s+|,\s*|\s+)?)+(?:made their first contribution(?:\s+in\s+\S+)?)?\.?$`)
+
 func parseReleaseBody(body string) ([]Section, []string) {
 	var sections []Section
 	var ungroupedChanges []string
 
-	headerRegex := regexp.MustCompile(`^#{1,3}\s+(.+)$`)
+	headerRegex := regexp.MustCompile(`^(#{1,6})\s+(.+)$`)
+	summaryRegex := regexp.MustCompile(`(?i)<summary>(.*?)</summary>`)
 	lines := strings.Split(body, "\n")
 
 	var currentSection *Section
+	// parentName is the last h1-h3 heading; deeper headings are named
+	// "Parent / Child" so their bullets stay distinct from the parent's.
+	var parentName string
+	// fence holds the lines of a fenced code block while one is open, with
+	// the fence's own indentation removed. A closed block is kept as a
+	// single multi-line change.
+	var fence []string
+	var fenceIndent string
+	// inDetails is set while a collapsible <details> block, whose <summary>
+	// named the current section, is open.
+	var inDetails bool
+
+	addChange := func(change string) {
+		if currentSection != nil {
+			currentSection.Changes = append(currentSection.Changes, change)
+		} else {
+			ungroupedChanges = append(ungroupedChanges, change)
+		}
+	}
 
 	for _, line := range lines {
 		trimmed := strings.TrimSpace(line)
 
-		// Check for section header (# ## or ###)
+		isFence := strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")
+		if fence != nil {
+			fence = append(fence, strings.TrimPrefix(strings.TrimRight(line, " \t\r"), fenceIndent))
+			if isFence {
+				addChange(strings.Join(fence, "\n"))
+				fence = nil
+			}
+			continue
+		}
+		if isFence {
+			fence = []string{trimmed}
+			fenceIndent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			continue
+		}
+
+		// Check for section header (# through ######)
 		if match := headerRegex.FindStringSubmatch(trimmed); match != nil {
-			headerName := strings.TrimSpace(match[1])
+			level := len(match[1])
+			headerName := strings.TrimSpace(match[2])
 			// Skip "What's Changed" as it's just a wrapper, not a real category
 			if headerName == "What's Changed" {
 				continue
 			}
+			if level <= 3 {
+				parentName = headerName
+			} else if parentName != "" {
+				headerName = parentName + " / " + headerName
+			}
 			// Save previous section if exists
 			if currentSection != nil && len(currentSection.Changes) > 0 {
 				sections = append(sections, *currentSection)
@@ -419,20 +2800,41 @@ func parseReleaseBody(body string) ([]Section, []string) {
 			continue
 		}
 
+		// A collapsible block's summary names its changes, like a heading
+		if match := summaryRegex.FindStringSubmatch(trimmed); match != nil {
+			if name := htmlText(match[1]); name != "" {
+				if currentSection != nil && len(currentSection.Changes) > 0 {
+					sections = append(sections, *currentSection)
+				}
+				currentSection = &Section{Name: name}
+				inDetails = true
+			}
+			continue
+		}
+		if inDetails && strings.EqualFold(trimmed, "</details>") {
+			if len(currentSection.Changes) > 0 {
+				sections = append(sections, *currentSection)
+			}
+			currentSection = nil
+			inDetails = false
+			continue
+		}
+
 		// Check for list item
 		if strings.HasPrefix(trimmed, "- ") || strings.HasPrefix(trimmed, "* ") {
 			change := strings.TrimPrefix(trimmed, "- ")
 			change = strings.TrimPrefix(change, "* ")
-			if change != "" && !strings.HasPrefix(change, "@") {
-				if currentSection != nil {
-					currentSection.Changes = append(currentSection.Changes, change)
-				} else {
-					ungroupedChanges = append(ungroupedChanges, change)
-				}
+			if change != "" && !contributorCredit.MatchString(change) {
+				addChange(change)
 			}
 		}
 	}
 
+	// Keep an unterminated code block rather than dropping it
+	if fence != nil {
+		addChange(strings.Join(fence, "\n"))
+	}
+
 	// Don't forget the last section
 	if currentSection != nil && len(currentSection.Changes) > 0 {
 		sections = append(sections, *currentSection)
@@ -441,14 +2843,23 @@ func parseReleaseBody(body string) ([]Section, []string) {
 	return sections, ungroupedChanges
 }
 
-func parseMarkdownChangelog(content, versionPattern string) []ChangelogEntry {
+func parseMarkdownChangelog(content, versionPattern string) ([]ChangelogEntry, error) {
 	var entries []ChangelogEntry
 
-	versionRegex := regexp.MustCompile(versionPattern)
+	versionRegex, err := regexp.Compile(versionPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if versionRegex.NumSubexp() < 1 {
+		return nil, fmt.Errorf("pattern %q needs a capture group for the version", versionPattern)
+	}
 	matches := versionRegex.FindAllStringSubmatchIndex(content, -1)
 
 	for i, match := range matches {
 		versionEnd := match[1]
+		if match[2] < 0 {
+			return nil, fmt.Errorf("heading %q did not capture a version", content[match[0]:match[1]])
+		}
 		ver := content[match[2]:match[3]]
 
 		var contentEnd int
@@ -467,19 +2878,29 @@ func parseMarkdownChangelog(content, versionPattern string) []ChangelogEntry {
 		})
 	}
 
-	return entries
+	return entries, nil
 }
 
-func parseMarkdownChangelogWithDate(content, versionPattern string) []ChangelogEntry {
+func parseMarkdownChangelogWithDate(content, versionPattern string) ([]ChangelogEntry, error) {
 	var entries []ChangelogEntry
 
-	versionRegex := regexp.MustCompile(versionPattern)
-	matches := versionRegex.FindAllStringSubmatch(content, -1)
+	versionRegex, err := regexp.Compile(versionPattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	if versionRegex.NumSubexp() < 2 {
+		return nil, fmt.Errorf("pattern %q needs two capture groups, for the version and the date", versionPattern)
+	}
 	matchIndexes := versionRegex.FindAllStringSubmatchIndex(content, -1)
 
-	for i, match := range matches {
-		ver := match[1]
-		dateStr := match[2]
+	for i, match := range matchIndexes {
+		// Each match holds start and end pairs for the heading, the version
+		// and the date; a group that did not take part is -1.
+		if len(match) < 6 || match[2] < 0 || match[4] < 0 {
+			return nil, fmt.Errorf("heading %q did not capture both a version and a date", content[match[0]:match[1]])
+		}
+		ver := content[match[2]:match[3]]
+		dateStr := content[match[4]:match[5]]
 
 		releasedAt, _ := time.Parse("2006-01-02", dateStr)
 
@@ -490,7 +2911,7 @@ func parseMarkdownChangelogWithDate(content, versionPattern string) []ChangelogE
 			contentEnd = len(content)
 		}
 
-		sectionContent := content[matchIndexes[i][1]:contentEnd]
+		sectionContent := content[match[1]:contentEnd]
 		changes := parseChanges(sectionContent)
 
 		entries = append(entries, ChangelogEntry{
@@ -500,7 +2921,7 @@ func parseMarkdownChangelogWithDate(content, versionPattern string) []ChangelogE
 		})
 	}
 
-	return entries
+	return entries, nil
 }
 
 func parseMarkdownChangelogWithOptionalDate(content, versionPattern string) []ChangelogEntry {
@@ -510,11 +2931,26 @@ func parseMarkdownChangelogWithOptionalDate(content, versionPattern string) []Ch
 	matches := versionRegex.FindAllStringSubmatch(content, -1)
 	matchIndexes := versionRegex.FindAllStringSubmatchIndex(content, -1)
 
+	// Patterns may name their groups; otherwise group 2 is the date.
+	dateGroup := versionRegex.SubexpIndex("date")
+	if dateGroup < 0 {
+		dateGroup = 2
+	}
+	toGroup := versionRegex.SubexpIndex("to")
+
 	for i, match := range matches {
 		ver := match[1]
+		var versionFrom string
+		if toGroup > 0 && match[toGroup] != "" {
+			versionFrom, ver = match[1], match[toGroup]
+			if compareVersions(versionFrom, ver) > 0 {
+				versionFrom, ver = ver, versionFrom
+			}
+		}
+
 		var releasedAt time.Time
-		if len(match) > 2 && match[2] != "" {
-			releasedAt, _ = time.Parse("2006-01-02", match[2])
+		if dateGroup < len(match) && match[dateGroup] != "" {
+			releasedAt, _ = time.Parse("2006-01-02", match[dateGroup])
 		}
 
 		var contentEnd int
@@ -528,9 +2964,10 @@ func parseMarkdownChangelogWithOptionalDate(content, versionPattern string) []Ch
 		changes := parseChanges(sectionContent)
 
 		entries = append(entries, ChangelogEntry{
-			Version:    ver,
-			ReleasedAt: releasedAt,
-			Changes:    changes,
+			Version:     ver,
+			VersionFrom: versionFrom,
+			ReleasedAt:  releasedAt,
+			Changes:     changes,
 		})
 	}
 
@@ -550,9 +2987,39 @@ func parseChanges(content string) []string {
 	return changes
 }
 
+// newRequest returns a GET request for url carrying aic's User-Agent.
+func newRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	return req, nil
+}
+
+// newGitHubRequest is newRequest for the GitHub API. Callers send the
+// request right away, so it first waits for githubLimiter.
+func newGitHubRequest(url string) (*http.Request, error) {
+	githubLimiter.wait()
+	req, err := newRequest(url)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	return req, nil
+}
+
 func httpGet(url string) (string, error) {
-	resp, err := http.Get(url)
+	req, err := newRequest(url)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
+		if isRedirectLoop(err) {
+			return "", &redirectLoopError{url: url}
+		}
 		return "", fmt.Errorf("HTTP request failed: %w", err)
 	}
 	defer resp.Body.Close()
@@ -566,7 +3033,35 @@ func httpGet(url string) (string, error) {
 		return "", fmt.Errorf("failed to read response: %w", err)
 	}
 
-	return string(body), nil
+	return cleanText(body), nil
+}
+
+// redirectLoopError reports a URL that kept redirecting, usually a
+// misconfigured mirror. fetchSource fills in the source it belongs to.
+type redirectLoopError struct {
+	url    string
+	source string
+}
+
+func (e *redirectLoopError) Error() string {
+	if e.source == "" {
+		return fmt.Sprintf("too many redirects fetching %s, check the source URL", e.url)
+	}
+	return fmt.Sprintf("too many redirects fetching %s for %s, check the source URL", e.url, e.source)
+}
+
+// isRedirectLoop reports whether err is the client giving up after too many
+// redirects. net/http doesn't export that error, so its message is matched.
+func isRedirectLoop(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && strings.HasPrefix(urlErr.Err.Error(), "stopped after ")
+}
+
+// cleanText converts fetched bytes to a string for parsing, dropping a
+// leading UTF-8 byte order mark and replacing invalid UTF-8.
+func cleanText(data []byte) string {
+	text := strings.TrimPrefix(string(data), "\uFEFF")
+	return strings.ToValidUTF8(text, "\uFFFD")
 }
 
 func outputJSON(entry *ChangelogEntry) {
@@ -578,49 +3073,574 @@ func outputJSON(entry *ChangelogEntry) {
 	}
 }
 
-func outputMarkdown(entry *ChangelogEntry) {
+// outputJSONArray writes entries as a single JSON array, in the order given.
+func outputJSONArray(w io.Writer, entries []ChangelogEntry) error {
+	if entries == nil {
+		entries = []ChangelogEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// outputShell writes each change of entry on its own line, quoted for a POSIX
+// shell, ignoring sections. The lines of a multi-line change, such as a
+// fenced code block, are joined with spaces so it stays on one line.
+func outputShell(w io.Writer, entry *ChangelogEntry) {
+	for _, change := range flattenSections(*entry).Changes {
+		fmt.Fprintln(w, shellQuote(strings.Join(strings.Fields(change), " ")))
+	}
+}
+
+// shellQuote quotes s as a single word for a POSIX shell: it is wrapped in
+// single quotes, and each single quote inside is closed, escaped and reopened.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// outputOptions holds formatter settings chosen on the command line.
+type outputOptions struct {
+	frontMatter   bool
+	mdNoDate      bool
+	mdNoSections  bool
+	stripPRLinks  bool
+	noUngrouped   bool
+	onlyUngrouped bool
+	head          int
+	wrap          int
+	noEmoji       bool
+	noSeparator   bool
+	// separatorWidth is the length of the dashed line under plain text
+	// headers; 0 means 40.
+	separatorWidth int
+	maxChangeLen   int
+	prefix         bool
+	theme          palette
+
+	normalizeSections bool
+	sortChanges       bool
+	flattenSingle     bool
+	flat              bool
+	annotateVersion   bool
+	stripMarkdown     bool
+	noMergeSections   bool
+	uniformSections   bool
+}
+
+// formatFlags parses the formatting flags shared by latest and the single
+// source commands into opts. Flags that only apply to JSON output are held
+// until finish, once the output format is known.
+type formatFlags struct {
+	opts            *outputOptions
+	themeName       string
+	minifyChanges   bool
+	uniformSections bool
+}
+
+func newFormatFlags(opts *outputOptions) *formatFlags {
+	opts.wrap = terminalWidth()
+	opts.separatorWidth = defaultSeparatorWidth()
+	return &formatFlags{opts: opts, themeName: "dark"}
+}
+
+// parse applies the formatting flag at args[i], returning the index of the
+// last argument it consumed. Anything else is left alone and i is returned
+// unchanged.
+func (f *formatFlags) parse(args []string, i int) int {
+	opts := f.opts
+	switch args[i] {
+	case "-head", "--head":
+		if i+1 < len(args) {
+			opts.head = parsePositiveFlag("-head", args[i+1])
+			i++
+		}
+	case "-wrap", "--wrap":
+		if i+1 < len(args) {
+			opts.wrap = parseWrapFlag(args[i+1])
+			i++
+		}
+	case "-no-emoji", "--no-emoji":
+		opts.noEmoji = true
+	case "-no-separator", "--no-separator":
+		opts.noSeparator = true
+	case "-separator-width", "--separator-width":
+		if i+1 < len(args) {
+			opts.separatorWidth = parsePositiveFlag("-separator-width", args[i+1])
+			i++
+		}
+	case "-prefix", "--prefix":
+		opts.prefix = true
+	case "-theme", "--theme":
+		if i+1 < len(args) {
+			f.themeName = args[i+1]
+			i++
+		}
+	case "-max-change-len", "--max-change-len":
+		if i+1 < len(args) {
+			opts.maxChangeLen = parsePositiveFlag("-max-change-len", args[i+1])
+			i++
+		}
+	case "-normalize-sections", "--normalize-sections":
+		opts.normalizeSections = true
+	case "-no-merge-sections", "--no-merge-sections":
+		opts.noMergeSections = true
+	case "-sort-changes", "--sort-changes":
+		opts.sortChanges = true
+	case "-flatten-single-section", "--flatten-single-section":
+		opts.flattenSingle = true
+	case "-flat", "--flat":
+		opts.flat = true
+	case "-annotate-version", "--annotate-version":
+		opts.annotateVersion = true
+	case "-json-minify-changes", "--json-minify-changes":
+		f.minifyChanges = true
+	case "-uniform-sections", "--uniform-sections":
+		f.uniformSections = true
+	}
+	return i
+}
+
+// finish resolves the theme and the JSON-only flags once all arguments are
+// parsed.
+func (f *formatFlags) finish(jsonOutput bool) {
+	if theme := parseThemeFlag(f.themeName); colorEnabled() {
+		f.opts.theme = theme
+	}
+	f.opts.stripMarkdown = f.minifyChanges && jsonOutput
+	f.opts.uniformSections = f.uniformSections && jsonOutput
+}
+
+// defaultSeparatorWidth sizes the plain text separator to the terminal, up to
+// 80 columns, or 40 when the width is unknown.
+func defaultSeparatorWidth() int {
+	if width := terminalWidth(); width > 0 {
+		return min(width, 80)
+	}
+	return 40
+}
+
+// parsePositiveFlag parses the value of flag, exiting on anything but a
+// positive integer.
+func parsePositiveFlag(flag, value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		fatalf("%s expects a positive number, got '%s'", flag, value)
+	}
+	return n
+}
+
+// parseWrapFlag parses the -wrap value, exiting on anything but a
+// non-negative integer. 0 disables wrapping.
+func parseWrapFlag(value string) int {
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		fatalf("-wrap expects a column count, got '%s'", value)
+	}
+	return n
+}
+
+// applyOutputOptions returns a copy of entry with the content filters in opts
+// applied, so every formatter sees the same changes.
+func applyOutputOptions(entry ChangelogEntry, opts outputOptions) ChangelogEntry {
+	if opts.normalizeSections {
+		entry = normalizeSections(entry)
+	}
+	if !opts.noMergeSections {
+		entry = mergeSections(entry)
+	}
+	if opts.flattenSingle {
+		entry = flattenSingleSection(entry)
+	}
+	if opts.sortChanges {
+		entry = sortChanges(entry)
+	}
+	if opts.noUngrouped {
+		entry.Changes = nil
+	}
+	if opts.onlyUngrouped {
+		entry.Sections = nil
+	}
+	if opts.flat {
+		entry = flattenSections(entry)
+	}
+	if opts.stripMarkdown {
+		entry = stripEntryMarkdown(entry)
+	}
+	if opts.annotateVersion {
+		entry = annotateVersion(entry)
+	}
+	if opts.head > 0 {
+		entry = truncateChanges(entry, opts.head)
+	}
+	if opts.uniformSections {
+		entry = foldUngrouped(entry)
+	}
+	return entry
+}
+
+// truncateChanges keeps the first n changes of entry in display order
+// (sections, then ungrouped changes) and records how many were dropped.
+func truncateChanges(entry ChangelogEntry, n int) ChangelogEntry {
+	remaining := n
+
+	var sections []Section
+	for _, section := range entry.Sections {
+		if remaining == 0 {
+			entry.omitted += len(section.Changes)
+			continue
+		}
+		if len(section.Changes) > remaining {
+			entry.omitted += len(section.Changes) - remaining
+			section.Changes = section.Changes[:remaining]
+		}
+		remaining -= len(section.Changes)
+		sections = append(sections, section)
+	}
+	entry.Sections = sections
+
+	if len(entry.Changes) > remaining {
+		entry.omitted += len(entry.Changes) - remaining
+		entry.Changes = entry.Changes[:remaining]
+	}
+
+	return entry
+}
+
+// categoryCounts returns the number of changes in each section of entry,
+// counting ungrouped changes as "Other".
+func categoryCounts(entry *ChangelogEntry) map[string]int {
+	counts := map[string]int{}
+	for _, section := range entry.Sections {
+		counts[section.Name] += len(section.Changes)
+	}
+	if len(entry.Changes) > 0 {
+		counts["Other"] += len(entry.Changes)
+	}
+	return counts
+}
+
+// outputCategoryCounts prints the category counts of entry as a JSON object.
+func outputCategoryCounts(entry *ChangelogEntry) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(categoryCounts(entry)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// versionCounts pairs a version with its category counts for -all output.
+type versionCounts struct {
+	Version string         `json:"version"`
+	Counts  map[string]int `json:"counts"`
+}
+
+// outputCategoryCountsArray prints the category counts of each entry as a
+// JSON array of per-version objects.
+func outputCategoryCountsArray(w io.Writer, entries []ChangelogEntry) {
+	items := []versionCounts{}
+	for i := range entries {
+		items = append(items, versionCounts{entries[i].Version, categoryCounts(&entries[i])})
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(items); err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// tocItem is one line of a table of contents: a section and its size.
+type tocItem struct {
+	Name    string `json:"name"`
+	Changes int    `json:"changes"`
+}
+
+// outputTOC prints the section names of entry with their change counts,
+// listing ungrouped changes as "Other".
+func outputTOC(displayName string, entry *ChangelogEntry, jsonOutput, mdOutput bool, opts outputOptions) {
+	var items []tocItem
+	for _, section := range entry.Sections {
+		items = append(items, tocItem{section.Name, len(section.Changes)})
+	}
+	if len(entry.Changes) > 0 {
+		items = append(items, tocItem{"Other", len(entry.Changes)})
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(items)
+		return
+	}
+
+	if mdOutput {
+		if !entry.ReleasedAt.IsZero() {
+			fmt.Printf("## %s (%s)\n\n", entry.Version, entry.ReleasedAt.Format("2006-01-02"))
+		} else {
+			fmt.Printf("## %s\n\n", entry.Version)
+		}
+		for _, item := range items {
+			fmt.Printf("- %s (%d)\n", item.Name, item.Changes)
+		}
+		return
+	}
+
 	if !entry.ReleasedAt.IsZero() {
-		fmt.Printf("## %s (%s)\n\n", entry.Version, entry.ReleasedAt.Format("2006-01-02"))
+		fmt.Printf("%s %s (%s)\n", displayName, entry.Version, entry.ReleasedAt.Format("2006-01-02"))
+	} else {
+		fmt.Printf("%s %s\n", displayName, entry.Version)
+	}
+	writeSeparator(os.Stdout, opts)
+	for _, item := range items {
+		fmt.Printf("  %s (%d)\n", item.Name, item.Changes)
+	}
+}
+
+func outputMarkdown(w io.Writer, displayName string, entry *ChangelogEntry, opts outputOptions) {
+	if opts.frontMatter {
+		fmt.Fprintln(w, "---")
+		fmt.Fprintf(w, "title: %q\n", displayName+" "+entry.Version)
+		if !entry.ReleasedAt.IsZero() {
+			fmt.Fprintf(w, "date: %s\n", entry.ReleasedAt.Format("2006-01-02"))
+		}
+		fmt.Fprintf(w, "source: %q\n", displayName)
+		fmt.Fprintln(w, "---")
+		fmt.Fprintln(w)
+	}
+
+	if !entry.ReleasedAt.IsZero() && !opts.mdNoDate {
+		fmt.Fprintf(w, "## %s (%s)\n\n", entry.Version, entry.ReleasedAt.Format("2006-01-02"))
 	} else {
-		fmt.Printf("## %s\n\n", entry.Version)
+		fmt.Fprintf(w, "## %s\n\n", entry.Version)
+	}
+
+	if opts.mdNoSections {
+		flat := flattenSections(*entry)
+		entry = &flat
 	}
 
 	// Output sectioned changes
 	for _, section := range entry.Sections {
-		fmt.Printf("### %s\n\n", section.Name)
+		fmt.Fprintf(w, "### %s\n\n", section.Name)
 		for _, change := range section.Changes {
-			fmt.Printf("- %s\n", change)
+			fmt.Fprintf(w, "- %s\n", markdownItem(truncateText(markdownChange(change, opts), opts.maxChangeLen)))
 		}
-		fmt.Println()
+		fmt.Fprintln(w)
 	}
 
 	// Output ungrouped changes
 	for _, change := range entry.Changes {
-		fmt.Printf("- %s\n", change)
+		fmt.Fprintf(w, "- %s\n", markdownItem(truncateText(markdownChange(change, opts), opts.maxChangeLen)))
+	}
+
+	if entry.omitted > 0 {
+		if len(entry.Changes) > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "_... %d more_\n", entry.omitted)
+	}
+}
+
+// markdownItem indents the continuation lines of a multi-line change so they
+// stay inside its list item.
+func markdownItem(change string) string {
+	return strings.ReplaceAll(change, "\n", "\n  ")
+}
+
+// writeSeparator writes the dashed line under a plain text header, unless
+// -no-separator is set.
+func writeSeparator(w io.Writer, opts outputOptions) {
+	if opts.noSeparator {
+		return
+	}
+	width := opts.separatorWidth
+	if width == 0 {
+		width = 40
 	}
+	fmt.Fprintln(w, strings.Repeat("-", width))
 }
 
-func outputPlainText(displayName string, entry *ChangelogEntry) {
+// outputPlainText prints entry as plain text headed by displayName. With
+// -prefix every line is prefixed with the source name, e.g. "[claude] ".
+func outputPlainText(name, displayName string, entry *ChangelogEntry, opts outputOptions) {
+	var b strings.Builder
+	theme := opts.theme
+	header := theme.paint(theme.header, displayName+" "+entry.Version)
 	if !entry.ReleasedAt.IsZero() {
-		fmt.Printf("%s %s (%s)\n", displayName, entry.Version, entry.ReleasedAt.Format("2006-01-02"))
+		fmt.Fprintf(&b, "%s %s\n", header, theme.paint(theme.date, "("+entry.ReleasedAt.Format("2006-01-02")+")"))
 	} else {
-		fmt.Printf("%s %s\n", displayName, entry.Version)
+		fmt.Fprintf(&b, "%s\n", header)
 	}
-	fmt.Println(strings.Repeat("-", 40))
+	writeSeparator(&b, opts)
 
-	// Output sectioned changes
-	for _, section := range entry.Sections {
-		fmt.Printf("\n[%s]\n", section.Name)
+	text := func(s string) string {
+		if opts.noEmoji {
+			return stripLeadingEmoji(s)
+		}
+		return s
+	}
+
+	bullet := theme.paint(theme.bullet, "*")
+
+	// Output sectioned changes, compactly when there is no separator
+	for i, section := range entry.Sections {
+		if i > 0 || !opts.noSeparator {
+			fmt.Fprintln(&b)
+		}
+		fmt.Fprintf(&b, "%s\n", theme.paint(theme.section, "["+text(section.Name)+"]"))
 		for _, change := range section.Changes {
-			fmt.Printf("  * %s\n", change)
+			fmt.Fprintf(&b, "  %s %s\n", bullet, wrapText(truncateText(text(change), opts.maxChangeLen), opts.wrap, 4))
 		}
 	}
 
 	// Output ungrouped changes
 	if len(entry.Sections) > 0 && len(entry.Changes) > 0 {
-		fmt.Println()
+		fmt.Fprintln(&b)
 	}
 	for _, change := range entry.Changes {
-		fmt.Printf("  * %s\n", change)
+		fmt.Fprintf(&b, "  %s %s\n", bullet, wrapText(truncateText(text(change), opts.maxChangeLen), opts.wrap, 4))
+	}
+
+	if entry.omitted > 0 {
+		fmt.Fprintf(&b, "  ... %d more\n", entry.omitted)
+	}
+
+	output := b.String()
+	if opts.prefix {
+		output = prefixLines(output, "["+name+"] ")
+	}
+	fmt.Print(output)
+}
+
+// prefixLines prepends prefix to every non-blank line of text.
+func prefixLines(text, prefix string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// truncateText shortens text to at most n runes, ending it with an ellipsis
+// when anything was cut. An n of 0 leaves text unchanged.
+func truncateText(text string, n int) string {
+	if n <= 0 || utf8.RuneCountInString(text) <= n {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:n-1]) + "…"
+}
+
+// wrapText word-wraps text to width columns, assuming the first line follows a
+// prefix of indent columns and indenting continuation lines to match. A width
+// of 0, or one too narrow to be useful, leaves text unchanged.
+func wrapText(text string, width, indent int) string {
+	if strings.Contains(text, "\n") {
+		// Multi-line changes, such as code blocks, keep their line breaks.
+		return strings.ReplaceAll(text, "\n", "\n"+strings.Repeat(" ", indent))
+	}
+
+	available := width - indent
+	if width <= 0 || available < 20 {
+		return text
+	}
+
+	var b strings.Builder
+	lineLen := 0
+	for i, word := range strings.Fields(text) {
+		wordLen := utf8.RuneCountInString(word)
+		if i > 0 {
+			if lineLen+1+wordLen > available {
+				b.WriteString("\n" + strings.Repeat(" ", indent))
+				lineLen = 0
+			} else {
+				b.WriteByte(' ')
+				lineLen++
+			}
+		}
+		b.WriteString(word)
+		lineLen += wordLen
+	}
+	return b.String()
+}
+
+var (
+	markdownImage    = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink     = regexp.MustCompile(`\[([^\]]+)\]\([^)]*\)`)
+	markdownEmphasis = regexp.MustCompile(`(\*\*|__|~~)(.+?)(\*\*|__|~~)`)
+	markdownItalic   = regexp.MustCompile(`(^|[\s(])[*_]([^*_\s][^*_]*?)[*_]([\s).,:;!?]|$)`)
+	markdownCode     = regexp.MustCompile("`([^`]+)`")
+
+	// markdownFence matches a fenced code block, capturing its body without
+	// the fence lines.
+	markdownFence = regexp.MustCompile("(?s)```[^\n]*\n(.*?)\n?```")
+)
+
+// stripMarkdown reduces markdown to plain text: links and images become their
+// text, and emphasis and code markers are removed. Fenced code blocks lose
+// their fence lines but keep their body as it is.
+func stripMarkdown(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range markdownFence.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(stripInlineMarkdown(s[last:m[0]]))
+		b.WriteString(s[m[2]:m[3]])
+		last = m[1]
+	}
+	b.WriteString(stripInlineMarkdown(s[last:]))
+	return b.String()
+}
+
+func stripInlineMarkdown(s string) string {
+	s = markdownImage.ReplaceAllString(s, "$1")
+	s = markdownLink.ReplaceAllString(s, "$1")
+	s = markdownCode.ReplaceAllString(s, "$1")
+	s = markdownEmphasis.ReplaceAllString(s, "$2")
+	s = markdownItalic.ReplaceAllString(s, "$1$2$3")
+	return s
+}
+
+// markdownChange returns change as -md prints it, before truncation.
+func markdownChange(change string, opts outputOptions) string {
+	if opts.stripPRLinks {
+		return stripPRLinks(change)
+	}
+	return change
+}
+
+// bareReviewLink matches a bare pull request or compare URL and the "in"
+// before it, as in "Fix crash by @someone in https://github.com/o/r/pull/1".
+// URLs inside markdown links aren't preceded by a space, so they're kept.
+var bareReviewLink = regexp.MustCompile(`(?:\s+in)?\s+https?://\S+/(?:pull|compare)/\S+`)
+
+// stripPRLinks removes bare pull request and compare URLs from s.
+func stripPRLinks(s string) string {
+	return strings.TrimSpace(bareReviewLink.ReplaceAllString(s, ""))
+}
+
+// stripLeadingEmoji removes emoji, and the spaces after them, from the start
+// of s.
+func stripLeadingEmoji(s string) string {
+	trimmed := strings.TrimLeftFunc(s, func(r rune) bool {
+		return isEmoji(r) || unicode.IsSpace(r)
+	})
+	if trimmed == strings.TrimLeftFunc(s, unicode.IsSpace) {
+		return s
+	}
+	return trimmed
+}
+
+// isEmoji reports whether r is an emoji or a character used to compose one,
+// such as a variation selector or zero-width joiner.
+func isEmoji(r rune) bool {
+	switch {
+	case r == 0x200D, r == 0x20E3, r >= 0xFE00 && r <= 0xFE0F:
+		return true
+	case r >= 0x1F000 && r <= 0x1FAFF, r >= 0xE0020 && r <= 0xE007F:
+		return true
 	}
+	return unicode.Is(unicode.So, r)
 }
@@ -0,0 +1,1273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// TestMain keeps tests off the user's cache; tests that need the cache use
+// useTempCache.
+func TestMain(m *testing.M) {
+	noCache = true
+	if args, ok := os.LookupEnv("AIC_TEST_ARGS"); ok {
+		os.Args = append([]string{"aic"}, strings.Split(args, "\n")...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runAIC runs the command line in a child process, since main exits on
+// errors, and returns its output and exit code.
+func runAIC(t *testing.T, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	return runAICWithConfig(t, "", args...)
+}
+
+// runAICWithConfig is runAIC with config as the contents of the config file.
+func runAICWithConfig(t *testing.T, config string, args ...string) (stdout, stderr string, code int) {
+	t.Helper()
+	configPath := filepath.Join(t.TempDir(), "config.toml")
+	if config != "" {
+		if err := os.WriteFile(configPath, []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(),
+		"AIC_TEST_ARGS="+strings.Join(args, "\n"),
+		"AIC_CONFIG="+configPath,
+		"NO_COLOR=1",
+	)
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), errOut.String(), code
+}
+
+// newTestGitHub serves handler over TLS and points GitHub requests, API and
+// raw files alike, at it for the rest of the test.
+func newTestGitHub(t *testing.T, handler http.Handler) *httptest.Server {
+	t.Helper()
+	s := httptest.NewTLSServer(handler)
+	oldHost, oldClient, oldLimiter := githubHost, http.DefaultClient, githubLimiter
+	githubHost = strings.TrimPrefix(s.URL, "https://")
+	http.DefaultClient = s.Client()
+	githubLimiter = nil
+	t.Cleanup(func() {
+		s.Close()
+		githubHost, http.DefaultClient, githubLimiter = oldHost, oldClient, oldLimiter
+	})
+	return s
+}
+
+// useTempCache points the cache at an empty directory and enables it for the
+// rest of the test.
+func useTempCache(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	old := noCache
+	noCache = false
+	t.Cleanup(func() { noCache = old })
+}
+
+func TestOutputShell(t *testing.T) {
+	entry := ChangelogEntry{
+		Sections: []Section{{Name: "Added", Changes: []string{"it's $HOME", "```yaml\nkey: value\n```"}}},
+		Changes:  []string{"plain"},
+	}
+	var buf bytes.Buffer
+	outputShell(&buf, &entry)
+
+	want := "'it'\\''s $HOME'\n'```yaml key: value ```'\n'plain'\n"
+	if buf.String() != want {
+		t.Errorf("outputShell wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStripMarkdown(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Add **bold** and __strong__ text", "Add bold and strong text"},
+		{"See [the docs](https://example.com)", "See the docs"},
+		{"Run `aic -json`", "Run aic -json"},
+		{"![logo](logo.png) Logo", "logo Logo"},
+		{"~~old~~ new", "old new"},
+		{"Config:\n```yaml\nkey: **value**\n```", "Config:\nkey: **value**"},
+		{"```\na\n```\nthen **b**", "a\nthen b"},
+	}
+	for _, tt := range tests {
+		if got := stripMarkdown(tt.in); got != tt.want {
+			t.Errorf("stripMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestJSONMinifyChanges(t *testing.T) {
+	entry := ChangelogEntry{
+		Version:  "1.0.0",
+		Sections: []Section{{Name: "Fixed", Changes: []string{"**Crash** in [parser](https://example.com/1)"}}},
+		Changes:  []string{"```sh\naic -json\n```"},
+	}
+
+	data, err := json.Marshal(applyOutputOptions(entry, outputOptions{stripMarkdown: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got ChangelogEntry
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if c := got.Sections[0].Changes[0]; c != "Crash in parser" {
+		t.Errorf("section change = %q, want %q", c, "Crash in parser")
+	}
+	if c := got.Changes[0]; c != "aic -json" {
+		t.Errorf("code block change = %q, want %q", c, "aic -json")
+	}
+
+	// Without the option the markdown is kept.
+	if kept := applyOutputOptions(entry, outputOptions{}); kept.Sections[0].Changes[0] != entry.Sections[0].Changes[0] {
+		t.Errorf("change without -json-minify-changes = %q, want it unchanged", kept.Sections[0].Changes[0])
+	}
+}
+
+func TestMarkdownSourceJoinsArchives(t *testing.T) {
+	dir := t.TempDir()
+	current := filepath.Join(dir, "CHANGELOG.md")
+	archive := filepath.Join(dir, "CHANGELOG-OLD.md")
+	os.WriteFile(current, []byte("# Changelog\n\n## 1.2.0\n\n- New\n\n## 1.1.0\n\n- Current\n"), 0o644)
+	os.WriteFile(archive, []byte("# Changelog\n\n## 1.1.0\n\n- Archived\n\n## 1.0.0\n\n- First\n"), 0o644)
+
+	src, err := customSource("tool", map[string]string{"kind": "markdown-raw", "urls": current + ", " + archive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := src.FetchFunc()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var versions []string
+	for _, entry := range entries {
+		versions = append(versions, entry.Version)
+	}
+	if got := strings.Join(versions, " "); got != "1.2.0 1.1.0 1.0.0" {
+		t.Fatalf("versions = %s, want 1.2.0 1.1.0 1.0.0", got)
+	}
+	if got := entries[1].Changes[0]; got != "Current" {
+		t.Errorf("1.1.0 change = %q, want the entry from the first file", got)
+	}
+}
+
+func TestFetchClaudeChangelogHeadingDates(t *testing.T) {
+	tests := []struct {
+		name      string
+		changelog string
+		want      []string
+	}{
+		{
+			name:      "dated",
+			changelog: "# Changelog\n\n## 1.2.3 (2024-05-01)\n\n- Dated\n\n## 1.2.2 (2024-04-20)\n\n- Older\n",
+			want:      []string{"2024-05-01", "2024-04-20"},
+		},
+		{
+			// The newest undated entry falls back to the commit date.
+			name:      "undated",
+			changelog: "# Changelog\n\n## 1.2.3\n\n- Undated\n\n## 1.2.2\n\n- Older\n",
+			want:      []string{"2024-06-01", ""},
+		},
+		{
+			name:      "mixed",
+			changelog: "# Changelog\n\n## 1.2.3\n\n- Undated\n\n## 1.2.2 (2024-04-20)\n\n- Dated\n",
+			want:      []string{"2024-06-01", "2024-04-20"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/anthropics/claude-code/raw/main/CHANGELOG.md":
+					fmt.Fprint(w, tt.changelog)
+				case "/api/v3/repos/anthropics/claude-code/commits":
+					fmt.Fprint(w, `[{"commit": {"message": "1.2.3", "committer": {"date": "2024-06-01T10:00:00Z"}}}]`)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+
+			entries, err := fetchClaudeChangelog()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d", len(entries), len(tt.want))
+			}
+			for i, want := range tt.want {
+				got := ""
+				if !entries[i].ReleasedAt.IsZero() {
+					got = entries[i].ReleasedAt.Format("2006-01-02")
+				}
+				if got != want {
+					t.Errorf("entry %s released %q, want %q", entries[i].Version, got, want)
+				}
+			}
+			if entries[0].Version != "1.2.3" {
+				t.Errorf("newest version = %s, want 1.2.3", entries[0].Version)
+			}
+		})
+	}
+}
+
+func TestFetchGitHubReleasesListsTagsOnce(t *testing.T) {
+	oldCommits, oldPages := includeCommits, maxPages
+	includeCommits, maxPages = true, 3
+	t.Cleanup(func() { includeCommits, maxPages = oldCommits, oldPages })
+
+	sha := strings.Repeat("a", 40)
+	tagCalls := 0
+	newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v3/repos/o/r/releases":
+			page := r.URL.Query().Get("page")
+			if page == "" {
+				page = "1"
+			}
+			if page != "3" {
+				w.Header().Set("Link", `<https://x/releases?page=3>; rel="next", <https://x/releases?page=3>; rel="last"`)
+			}
+			fmt.Fprintf(w, `[{"tag_name": "v1.%s.0", "body": "- Change"}]`, page)
+		case "/api/v3/repos/o/r/tags":
+			tagCalls++
+			fmt.Fprintf(w, `[{"name": "v1.1.0", "commit": {"sha": %q}}, {"name": "v1.3.0", "commit": {"sha": %q}}]`, sha, sha)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	entries, err := fetchGitHubReleases("o", "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	if tagCalls != 1 {
+		t.Errorf("tags listed %d times, want 1", tagCalls)
+	}
+	if entries[0].Commit != sha || entries[2].Commit != sha {
+		t.Errorf("commits = %q, %q, want %s on both", entries[0].Commit, entries[2].Commit, sha)
+	}
+}
+
+func TestFetchSourceRedirectLoop(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	}))
+	defer s.Close()
+
+	url := s.URL + "/CHANGELOG.md"
+	src := Source{
+		Name:        "mirror",
+		DisplayName: "Mirror",
+		FetchFunc: func() ([]ChangelogEntry, error) {
+			_, err := httpGet(url)
+			return nil, err
+		},
+	}
+
+	_, err := fetchSource(src)
+	want := "too many redirects fetching " + url + " for Mirror, check the source URL"
+	if err == nil || err.Error() != want {
+		t.Fatalf("fetchSource error = %v, want %q", err, want)
+	}
+}
+
+func TestConflictingOutputFormats(t *testing.T) {
+	tests := [][]string{
+		{"claude", "-json", "-md"},
+		{"claude", "-md", "-shell"},
+		{"claude", "-json", "-shell"},
+		{"claude", "-json", "-template", "{{.Version}}"},
+	}
+	for _, args := range tests {
+		_, stderr, code := runAIC(t, args...)
+		if code != 1 || !strings.Contains(stderr, "only one output format may be specified") {
+			t.Errorf("aic %s: exit %d, stderr %q; want the format conflict error", strings.Join(args, " "), code, stderr)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name, content, format string
+		version, date         string
+		section               string
+	}{
+		{
+			name:    "keep a changelog",
+			content: "# Changelog\n\n## [Unreleased]\n\n## [1.2.0] - 2024-02-01\n\n### Added\n\n- Thing\n",
+			format:  "keepachangelog", version: "1.2.0", date: "2024-02-01", section: "Added",
+		},
+		{
+			name:    "plain markdown",
+			content: "# Changelog\n\n## 1.2.0 (2024-02-01)\n\n- Thing\n",
+			format:  "markdown", version: "1.2.0", date: "2024-02-01",
+		},
+		{
+			name:    "github style",
+			content: "# [1.2.0](https://github.com/o/r/compare/v1.1.0...v1.2.0) (2024-02-01)\n\n### Bug Fixes\n\n* thing\n",
+			format:  "github", version: "1.2.0", date: "2024-02-01", section: "Bug Fixes",
+		},
+		{
+			name:    "unknown falls back to markdown",
+			content: "Release notes\n\nNothing here.\n",
+			format:  "markdown",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectFormat(tt.content); got != tt.format {
+				t.Fatalf("detectFormat = %q, want %q", got, tt.format)
+			}
+			entries, err := parseChangelogContent(tt.content, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.version == "" {
+				if len(entries) != 0 {
+					t.Errorf("got %d entries, want none", len(entries))
+				}
+				return
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			entry := entries[0]
+			if entry.Version != tt.version || entry.ReleasedAt.Format("2006-01-02") != tt.date {
+				t.Errorf("entry = %s (%s), want %s (%s)", entry.Version, entry.ReleasedAt.Format("2006-01-02"), tt.version, tt.date)
+			}
+			if tt.section != "" && (len(entry.Sections) != 1 || entry.Sections[0].Name != tt.section) {
+				t.Errorf("sections = %+v, want one %q section", entry.Sections, tt.section)
+			}
+		})
+	}
+}
+
+func TestReleaseDateFrom(t *testing.T) {
+	tests := []struct {
+		name, dateFrom, relName, body, want string
+	}{
+		{"published by default", "published", "v1.2.3 (2024-05-01)", "", "2024-05-03"},
+		{"date in name", "name", "v1.2.3 (2024-05-01)", "", "2024-05-01"},
+		{"date on first body line", "name", "v1.2.3", "Released 2024-04-30\n\n- Fix", "2024-04-30"},
+		{"name wins over body", "name", "v1.2.3 (2024-05-01)", "Released 2024-04-30", "2024-05-01"},
+		{"no date falls back to published", "name", "v1.2.3", "- Fix\n- Backport of 2024-04-30 fix", "2024-05-03"},
+	}
+	old := releaseDateFrom
+	t.Cleanup(func() { releaseDateFrom = old })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releaseDateFrom = tt.dateFrom
+			body, _ := json.Marshal([]map[string]string{{
+				"tag_name":     "v1.2.3",
+				"name":         tt.relName,
+				"body":         tt.body,
+				"published_at": "2024-05-03T10:00:00Z",
+			}})
+			entries, err := parseGitHubReleases(body, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := entries[0].ReleasedAt.Format("2006-01-02"); got != tt.want {
+				t.Errorf("released %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitHubErrorObject(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"error object", `{"message": "API rate limit exceeded", "documentation_url": "https://docs.github.com"}`, "GitHub API error: API rate limit exceeded"},
+		{"other object", `{"tag_name": "v1"}`, "failed to parse releases"},
+		{"not json", `<html>`, "failed to parse releases"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseGitHubReleases([]byte(tt.body), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchGitHubReleasesErrorStatus(t *testing.T) {
+	newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "API rate limit exceeded for 127.0.0.1."}`)
+	}))
+	_, err := fetchGitHubReleases("owner", "repo")
+	want := "GitHub API error (HTTP 403): API rate limit exceeded for 127.0.0.1."
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %v, want it to contain %q", err, want)
+	}
+}
+
+func TestCommitDateForVersion(t *testing.T) {
+	const commits = `[
+		{"commit": {"message": "Fix typo in changelog", "committer": {"date": "2024-05-03T10:00:00Z"}}},
+		{"commit": {"message": "chore: Update CHANGELOG.md for 2.0.70", "committer": {"date": "2024-05-02T10:00:00Z"}}},
+		{"commit": {"message": "Release v2.0.7", "committer": {"date": "2024-04-20T10:00:00Z"}}}
+	]`
+	newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/repos/owner/repo/commits" || r.URL.Query().Get("path") != "CHANGELOG.md" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, commits)
+	}))
+	got, err := fetchGitHubFileCommits("owner", "repo", "CHANGELOG.md", 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d commits, want 3", len(got))
+	}
+
+	tests := []struct {
+		version, want string
+	}{
+		{"2.0.70", "2024-05-02"},
+		{"2.0.7", "2024-04-20"},
+		{"2.0.71", "2024-05-03"},
+	}
+	for _, tt := range tests {
+		if date := commitDateForVersion(got, tt.version).Format("2006-01-02"); date != tt.want {
+			t.Errorf("commitDateForVersion(%s) = %s, want %s", tt.version, date, tt.want)
+		}
+	}
+	if date := commitDateForVersion(nil, "2.0.70"); !date.IsZero() {
+		t.Errorf("commitDateForVersion without commits = %s, want the zero time", date)
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	const long = "Fix a crash when the changelog has a very long heading line"
+	tests := []struct {
+		name, text    string
+		width, indent int
+		want          string
+	}{
+		{"no wrap", long, 0, 4, long},
+		{"fits", "Short change", 40, 4, "Short change"},
+		{"wraps at 30", long, 30, 4, "Fix a crash when the\n    changelog has a very long\n    heading line"},
+		{"too narrow", long, 20, 4, long},
+		{"long word", "See https://example.com/a/very/long/path/that/cannot/break", 30, 4, "See\n    https://example.com/a/very/long/path/that/cannot/break"},
+		{"multi-line keeps breaks", "Config:\nkey: value", 30, 4, "Config:\n    key: value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, tt.width, tt.indent)
+			if got != tt.want {
+				t.Errorf("wrapText = %q, want %q", got, tt.want)
+			}
+			if tt.width > 0 && !strings.Contains(tt.text, "\n") {
+				for _, line := range strings.Split(got, "\n")[1:] {
+					if len(line) > tt.width && strings.Contains(strings.TrimSpace(line), " ") {
+						t.Errorf("line %q is wider than %d columns", line, tt.width)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestParseReleaseBodyDeepHeadings(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       []Section
+	}{
+		{
+			name: "h4 under h3",
+			body: "### Features\n\n- Top feature\n\n#### CLI\n\n- New flag\n\n#### API\n\n- New endpoint\n\n### Fixes\n\n- Crash",
+			want: []Section{
+				{Name: "Features", Changes: []string{"Top feature"}},
+				{Name: "Features / CLI", Changes: []string{"New flag"}},
+				{Name: "Features / API", Changes: []string{"New endpoint"}},
+				{Name: "Fixes", Changes: []string{"Crash"}},
+			},
+		},
+		{
+			name: "h5 and h6",
+			body: "## Changes\n\n##### Minor\n\n- Tweak\n\n###### Docs\n\n- Typo",
+			want: []Section{
+				{Name: "Changes / Minor", Changes: []string{"Tweak"}},
+				{Name: "Changes / Docs", Changes: []string{"Typo"}},
+			},
+		},
+		{
+			name: "h4 without parent",
+			body: "#### Internal\n\n- Refactor",
+			want: []Section{{Name: "Internal", Changes: []string{"Refactor"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections, ungrouped := parseReleaseBody(tt.body)
+			if len(ungrouped) != 0 {
+				t.Errorf("ungrouped changes %q, want none", ungrouped)
+			}
+			if !reflect.DeepEqual(sections, tt.want) {
+				t.Errorf("sections = %+v, want %+v", sections, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputJSONArray(t *testing.T) {
+	tests := []struct {
+		name     string
+		entries  []ChangelogEntry
+		versions []string
+	}{
+		{"none", nil, []string{}},
+		{"one", []ChangelogEntry{{Version: "1.0.0"}}, []string{"1.0.0"}},
+		{"keeps order", []ChangelogEntry{{Version: "2.0.0"}, {Version: "1.10.0"}, {Version: "1.9.0"}}, []string{"2.0.0", "1.10.0", "1.9.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := outputJSONArray(&buf, tt.entries); err != nil {
+				t.Fatal(err)
+			}
+			var got []ChangelogEntry
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("output is not a JSON array: %v\n%s", err, buf.String())
+			}
+			versions := []string{}
+			for _, entry := range got {
+				versions = append(versions, entry.Version)
+			}
+			if !reflect.DeepEqual(versions, tt.versions) {
+				t.Errorf("versions = %v, want %v", versions, tt.versions)
+			}
+		})
+	}
+}
+
+func TestAllJSONIsOneArray(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	os.WriteFile(path, []byte("# Changelog\n\n## 1.10.0\n\n- Ten\n\n## 1.9.0\n\n- Nine\n\n## 1.2.0\n\n- Two\n"), 0o644)
+
+	stdout, stderr, code := runAIC(t, "file", path, "-all", "-json")
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, stderr)
+	}
+	var got []ChangelogEntry
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("output is not a single JSON array: %v\n%s", err, stdout)
+	}
+	var versions []string
+	for _, entry := range got {
+		versions = append(versions, entry.Version)
+	}
+	if want := []string{"1.10.0", "1.9.0", "1.2.0"}; !reflect.DeepEqual(versions, want) {
+		t.Errorf("versions = %v, want %v", versions, want)
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		text string
+		n    int
+		want string
+	}{
+		{"short", 0, "short"},
+		{"short", 5, "short"},
+		{"shorter", 5, "shor…"},
+		{"héllo wörld", 7, "héllo …"},
+		{"日本語のテキスト", 4, "日本語…"},
+		{"日本語", 3, "日本語"},
+		{"🎉🎉🎉🎉", 2, "🎉…"},
+	}
+	for _, tt := range tests {
+		got := truncateText(tt.text, tt.n)
+		if got != tt.want {
+			t.Errorf("truncateText(%q, %d) = %q, want %q", tt.text, tt.n, got, tt.want)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("truncateText(%q, %d) = %q is not valid UTF-8", tt.text, tt.n, got)
+		}
+	}
+}
+
+func TestMaxChangeLenKeepsJSON(t *testing.T) {
+	entry := ChangelogEntry{Version: "1.0.0", Changes: []string{"日本語のテキスト"}}
+	opts := outputOptions{maxChangeLen: 4}
+
+	var md bytes.Buffer
+	outputMarkdown(&md, "Tool", &entry, opts)
+	if !strings.Contains(md.String(), "- 日本語…\n") {
+		t.Errorf("markdown output %q, want the change truncated", md.String())
+	}
+
+	data, err := json.Marshal(applyOutputOptions(entry, opts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "日本語のテキスト") {
+		t.Errorf("JSON output %s, want the full change", data)
+	}
+}
+
+func TestParseReleaseBodyFencedCode(t *testing.T) {
+	tests := []struct {
+		name, body string
+		want       []Section
+		ungrouped  []string
+	}{
+		{
+			name: "block in a section",
+			body: "### Config\n\n- New key\n\n```toml\n[aic]\nkey = \"value\"\n```\n\n- After",
+			want: []Section{{Name: "Config", Changes: []string{"New key", "```toml\n[aic]\nkey = \"value\"\n```", "After"}}},
+		},
+		{
+			name:      "block outside sections",
+			body:      "Example:\n\n~~~\naic -json\n~~~",
+			ungrouped: []string{"~~~\naic -json\n~~~"},
+		},
+		{
+			name: "indented block under a bullet",
+			body: "### Added\n\n- Flag\n  ```sh\n  aic -flat\n  ```",
+			want: []Section{{Name: "Added", Changes: []string{"Flag", "```sh\naic -flat\n```"}}},
+		},
+		{
+			name: "unterminated block is kept",
+			body: "### Added\n\n```\naic -json",
+			want: []Section{{Name: "Added", Changes: []string{"```\naic -json"}}},
+		},
+		{
+			name: "bullets inside a block are code",
+			body: "### Added\n\n```\n- not a change\n## not a heading\n```",
+			want: []Section{{Name: "Added", Changes: []string{"```\n- not a change\n## not a heading\n```"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections, ungrouped := parseReleaseBody(tt.body)
+			if !reflect.DeepEqual(sections, tt.want) {
+				t.Errorf("sections = %q, want %q", sections, tt.want)
+			}
+			if !reflect.DeepEqual(ungrouped, tt.ungrouped) {
+				t.Errorf("ungrouped = %q, want %q", ungrouped, tt.ungrouped)
+			}
+		})
+	}
+}
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"plain", []byte("## 1.0.0"), "## 1.0.0"},
+		{"bom", []byte("\xef\xbb\xbf## 1.0.0"), "## 1.0.0"},
+		{"bom only at the start", []byte("a\xef\xbb\xbfb"), "a\ufeffb"},
+		{"invalid utf-8", []byte("caf\xe9 \xff\xfe"), "caf� �"},
+	}
+	for _, tt := range tests {
+		if got := cleanText(tt.data); got != tt.want {
+			t.Errorf("%s: cleanText = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBOMPrefixedChangelog(t *testing.T) {
+	const changelog = "\xef\xbb\xbf## 2.0.0\n\n- Caf\xe9 fix\n\n## 1.0.0\n\n- First\n"
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	os.WriteFile(path, []byte(changelog), 0o644)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, changelog)
+	}))
+	defer server.Close()
+
+	for _, location := range []string{path, server.URL + "/CHANGELOG.md"} {
+		entries, err := fetchFileChangelog(location, "", "")
+		if err != nil {
+			t.Fatalf("%s: %v", location, err)
+		}
+		if len(entries) != 2 || entries[0].Version != "2.0.0" {
+			t.Fatalf("%s: got %d entries starting %+v, want 2.0.0 first", location, len(entries), entries)
+		}
+		if got := entries[0].Changes[0]; got != "Caf� fix" {
+			t.Errorf("%s: change = %q, want the bad byte replaced", location, got)
+		}
+	}
+}
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stdout, fn)
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	return captureFile(t, &os.Stderr, fn)
+}
+
+func captureFile(t *testing.T, f **os.File, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := *f
+	*f = w
+	defer func() { *f = old }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+// testSource returns a source serving entries without any network access.
+func testSource(name string, entries ...ChangelogEntry) Source {
+	return Source{
+		Name:        name,
+		DisplayName: strings.ToUpper(name),
+		FetchFunc:   func() ([]ChangelogEntry, error) { return entries, nil },
+	}
+}
+
+func TestMergedEntriesCarrySource(t *testing.T) {
+	released := time.Now().Add(-24 * time.Hour)
+	srcs := []Source{
+		testSource("one", ChangelogEntry{Version: "1.1.0", ReleasedAt: released}, ChangelogEntry{Version: "1.0.0", ReleasedAt: released}),
+		testSource("two", ChangelogEntry{Version: "2.0.0", ReleasedAt: released}),
+	}
+
+	tests := []struct {
+		name string
+		run  func() []ChangelogEntry
+	}{
+		{"multi-source json", func() []ChangelogEntry {
+			var got []ChangelogEntry
+			out := captureStdout(t, func() { runMultiSource(srcs, "", false, true, false, 0, outputOptions{}) })
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatal(err)
+			}
+			return got
+		}},
+		{"all json", func() []ChangelogEntry {
+			var keyed map[string]ChangelogEntry
+			out := captureStdout(t, func() { runAllJSON(srcs, "", 0, outputOptions{}) })
+			if err := json.Unmarshal([]byte(out), &keyed); err != nil {
+				t.Fatal(err)
+			}
+			return []ChangelogEntry{keyed["one"], keyed["two"]}
+		}},
+		{"compare", func() []ChangelogEntry {
+			released, _ := fetchReleasedSince(srcs, time.Now().Add(-7*24*time.Hour))
+			return slices.Concat(released...)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.run()
+			if len(got) == 0 {
+				t.Fatal("no entries")
+			}
+			for _, entry := range got {
+				want := map[string]string{"1": "ONE", "2": "TWO"}[entry.Version[:1]]
+				if entry.Source != want {
+					t.Errorf("%s source = %q, want %q", entry.Version, entry.Source, want)
+				}
+			}
+		})
+	}
+}
+
+func TestVersionRangeHeading(t *testing.T) {
+	const changelog = "# Changelog\n\n## 1.3.0\n\n- Three\n\n## 1.2.0 - 1.2.3 (2024-03-01)\n\n- Range\n\n## 1.1.0 – 1.1.2\n\n- En dash\n\n## 1.0.0\n\n- First\n"
+	entries, err := parseChangelogContent(changelog, "markdown", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var headings []string
+	for _, entry := range entries {
+		headings = append(headings, entry.VersionFrom+".."+entry.Version)
+	}
+	if want := []string{"..1.3.0", "1.2.0..1.2.3", "1.1.0..1.1.2", "..1.0.0"}; !reflect.DeepEqual(headings, want) {
+		t.Fatalf("entries = %v, want %v", headings, want)
+	}
+	if got := entries[1].ReleasedAt.Format("2006-01-02"); got != "2024-03-01" {
+		t.Errorf("range date = %s, want 2024-03-01", got)
+	}
+
+	tests := []struct {
+		version, want string
+	}{
+		{"", "1.3.0"},
+		{"1.2.3", "1.2.3"},
+		{"1.2.0", "1.2.3"},
+		{"1.2.1", "1.2.3"},
+		{"1.1.1", "1.1.2"},
+		{"1.2.4", ""},
+		{"1.0.1", ""},
+	}
+	for _, tt := range tests {
+		got := ""
+		if entry := selectEntry(entries, tt.version); entry != nil {
+			got = entry.Version
+		}
+		if got != tt.want {
+			t.Errorf("selectEntry(%q) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{"default", "", nil, "aic/" + version},
+		{"env", "tool/1.0", nil, "tool/1.0"},
+		{"flag over env", "tool/1.0", []string{"-user-agent", "script/2.0"}, "script/2.0"},
+	}
+	old := userAgent
+	t.Cleanup(func() { userAgent = old })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("AIC_USER_AGENT", tt.env)
+			t.Setenv("AIC_GITHUB_HOST", "")
+			if _, err := parseGlobalFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			var agents []string
+			var mu sync.Mutex
+			s := newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				agents = append(agents, r.Header.Get("User-Agent"))
+				mu.Unlock()
+				if strings.HasPrefix(r.URL.Path, "/api/v3/") {
+					fmt.Fprint(w, "[]")
+				} else {
+					fmt.Fprint(w, "## 1.0.0\n")
+				}
+			}))
+			if _, err := httpGet(s.URL + "/owner/repo/raw/main/CHANGELOG.md"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fetchGitHubFileCommits("owner", "repo", "CHANGELOG.md", 1); err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{tt.want, tt.want}; !reflect.DeepEqual(agents, want) {
+				t.Errorf("User-Agent headers = %q, want %q", agents, want)
+			}
+		})
+	}
+}
+
+func TestFetchGitHubReleasesDedupesAcrossPages(t *testing.T) {
+	oldPages := maxPages
+	maxPages = 5
+	t.Cleanup(func() { maxPages = oldPages })
+
+	// v1.3.0 was published between the first and second page requests,
+	// pushing v1.1.0 onto page two as well.
+	pages := map[string]string{
+		"1": `[{"tag_name": "v1.2.0", "body": "- Two"}, {"tag_name": "v1.1.0", "body": "- One (page 1)"}]`,
+		"2": `[{"tag_name": "v1.1.0", "body": "- One (page 2)"}, {"tag_name": "v1.0.0", "body": "- Zero"}]`,
+	}
+	newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		if page == "" {
+			page = "1"
+		}
+		if page == "1" {
+			w.Header().Set("Link", `<https://x/releases?page=2>; rel="next", <https://x/releases?page=2>; rel="last"`)
+		}
+		fmt.Fprint(w, pages[page])
+	}))
+
+	entries, err := fetchGitHubReleases("o", "r")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, entry := range entries {
+		versions = append(versions, entry.Version)
+	}
+	if want := []string{"1.2.0", "1.1.0", "1.0.0"}; !reflect.DeepEqual(versions, want) {
+		t.Fatalf("versions = %v, want %v", versions, want)
+	}
+	if got := entries[1].Changes[0]; got != "One (page 1)" {
+		t.Errorf("1.1.0 change = %q, want the first occurrence", got)
+	}
+}
+
+func TestParseReleaseBodyContributorCredits(t *testing.T) {
+	tests := []struct {
+		change string
+		kept   bool
+	}{
+		{"@alice made their first contribution in https://github.com/o/r/pull/1", false},
+		{"@alice made their first contribution", false},
+		{"@dependabot[bot] made their first contribution in https://github.com/o/r/pull/2", false},
+		{"@alice, @bob and @carol", false},
+		{"@alice @bob", false},
+		{"@alice thanks for reporting the crash on startup", true},
+		{"@types/node updated to 20", true},
+		{"Fix crash reported by @alice", true},
+	}
+	for _, tt := range tests {
+		_, changes := parseReleaseBody("- " + tt.change)
+		if kept := len(changes) == 1; kept != tt.kept {
+			t.Errorf("%q kept = %v, want %v", tt.change, kept, tt.kept)
+		}
+	}
+}
+
+func TestPatternWithoutCaptureGroup(t *testing.T) {
+	const changelog = "## 1.0.0 (2024-01-01)\n\n- First\n"
+	tests := []struct {
+		name  string
+		parse func(content, pattern string) ([]ChangelogEntry, error)
+	}{
+		{"markdown", parseMarkdownChangelog},
+		{"markdown with date", parseMarkdownChangelogWithDate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := tt.parse(changelog, `(?m)^## \d+\.\d+\.\d+`)
+			if err == nil || !strings.Contains(err.Error(), "capture group") {
+				t.Errorf("got %d entries, error %v; want a capture group error", len(entries), err)
+			}
+		})
+	}
+
+	path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+	os.WriteFile(path, []byte(changelog), 0o644)
+	_, stderr, code := runAIC(t, "file", path, "-pattern", `^## \d+`)
+	if code != 1 || !strings.Contains(stderr, "needs a capture group for the version") {
+		t.Errorf("aic file -pattern without a group: exit %d, stderr %q", code, stderr)
+	}
+}
+
+func TestParseMarkdownChangelogWithDateGroups(t *testing.T) {
+	const changelog = "## 1.1.0\n\n- Undated\n\n## 1.0.0 (2024-01-01)\n\n- First\n"
+	tests := []struct {
+		name, pattern, wantErr string
+		versions               []string
+	}{
+		{"one group", `(?m)^## (\d+\.\d+\.\d+)`, "needs two capture groups", nil},
+		{"optional date missing", `(?m)^## (\d+\.\d+\.\d+)(?: \((\d{4}-\d{2}-\d{2})\))?$`, `heading "## 1.1.0" did not capture both a version and a date`, nil},
+		{"two groups", `(?m)^## (\d+\.\d+\.\d+) \((\d{4}-\d{2}-\d{2})\)$`, "", []string{"1.0.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := parseMarkdownChangelogWithDate(changelog, tt.pattern)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var versions []string
+			for _, entry := range entries {
+				versions = append(versions, entry.Version)
+			}
+			if !reflect.DeepEqual(versions, tt.versions) {
+				t.Errorf("versions = %v, want %v", versions, tt.versions)
+			}
+			if got := entries[0].ReleasedAt.Format("2006-01-02"); got != "2024-01-01" {
+				t.Errorf("date = %s, want 2024-01-01", got)
+			}
+		})
+	}
+}
+
+func TestMultiSourceListJSON(t *testing.T) {
+	dir := t.TempDir()
+	aa := filepath.Join(dir, "aa.md")
+	bb := filepath.Join(dir, "bb.md")
+	os.WriteFile(aa, []byte("## 1.2.0\n\n- Two\n\n## 1.1.0\n\n- One\n"), 0o644)
+	os.WriteFile(bb, []byte("## 0.5.0\n\n- Five\n"), 0o644)
+	config := fmt.Sprintf("[sources.aa]\nkind = \"markdown-raw\"\nurls = %q\n\n[sources.bb]\nkind = \"markdown-raw\"\nurls = %q\n", aa, bb)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"json", []string{"aa", "bb", "-list", "-json"}, "{\n  \"aa\": [\n    \"1.2.0\",\n    \"1.1.0\"\n  ],\n  \"bb\": [\n    \"0.5.0\"\n  ]\n}\n"},
+		{"plain", []string{"aa", "bb", "-list"}, "aa:\n1.2.0\n1.1.0\n\nbb:\n0.5.0\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runAICWithConfig(t, config, tt.args...)
+			if code != 0 {
+				t.Fatalf("exit %d: %s", code, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestSinceDayIsIncluded(t *testing.T) {
+	day := parseSinceFlag("2024-06-05")
+	src := testSource("bb",
+		ChangelogEntry{Version: "1.2.0", ReleasedAt: time.Date(2024, 6, 5, 15, 0, 0, 0, time.UTC)},
+		ChangelogEntry{Version: "1.1.0", ReleasedAt: time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC)},
+		ChangelogEntry{Version: "1.0.0", ReleasedAt: time.Date(2024, 6, 4, 23, 59, 0, 0, time.UTC)},
+	)
+	entries, _ := src.FetchFunc()
+
+	tests := []struct {
+		name string
+		got  func() []ChangelogEntry
+	}{
+		{"compare and digest", func() []ChangelogEntry {
+			released, _ := fetchReleasedSince([]Source{src}, day)
+			return released[0]
+		}},
+		{"-list -since", func() []ChangelogEntry {
+			return entriesBetween(entries, day, time.Time{})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var versions []string
+			for _, entry := range tt.got() {
+				versions = append(versions, entry.Version)
+			}
+			if want := []string{"1.2.0", "1.1.0"}; !reflect.DeepEqual(versions, want) {
+				t.Errorf("versions = %v, want %v", versions, want)
+			}
+		})
+	}
+}
+
+func TestFetchGitHubReleasesSecondPageFails(t *testing.T) {
+	oldPages, oldBestEffort := maxPages, bestEffort
+	maxPages = 3
+	t.Cleanup(func() { maxPages, bestEffort = oldPages, oldBestEffort })
+
+	newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Link", `<https://x/releases?page=2>; rel="next", <https://x/releases?page=3>; rel="last"`)
+		fmt.Fprint(w, `[{"tag_name": "v1.2.0", "body": "- Two"}, {"tag_name": "v1.1.0", "body": "- One"}]`)
+	}))
+
+	versions := func(entries []ChangelogEntry) []string {
+		var vs []string
+		for _, entry := range entries {
+			vs = append(vs, entry.Version)
+		}
+		return vs
+	}
+	page1 := []string{"1.2.0", "1.1.0"}
+
+	entries, err := fetchGitHubReleases("o", "r")
+	if err == nil || !strings.Contains(err.Error(), "releases page 2: HTTP 500") {
+		t.Errorf("error = %v, want the wrapped page 2 failure", err)
+	}
+	if !reflect.DeepEqual(versions(entries), page1) {
+		t.Errorf("versions = %v, want the page 1 entries %v", versions(entries), page1)
+	}
+
+	src := Source{
+		Name:        "tool",
+		DisplayName: "Tool",
+		FetchFunc:   func() ([]ChangelogEntry, error) { return fetchGitHubReleases("o", "r") },
+	}
+	tests := []struct {
+		name       string
+		bestEffort bool
+		want       []string
+		wantErr    bool
+	}{
+		{"without -best-effort", false, nil, true},
+		{"with -best-effort", true, page1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bestEffort = tt.bestEffort
+			var entries []ChangelogEntry
+			var err error
+			warning := captureStderr(t, func() { entries, err = fetchSource(src) })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), "releases page 2") {
+					t.Errorf("error = %v, want the page 2 failure", err)
+				}
+				return
+			}
+			if !reflect.DeepEqual(versions(entries), tt.want) {
+				t.Errorf("versions = %v, want %v", versions(entries), tt.want)
+			}
+			if !strings.Contains(warning, "Warning: Failed to fetch all of Tool") {
+				t.Errorf("stderr = %q, want a partial fetch warning", warning)
+			}
+		})
+	}
+}
+
+func TestEntriesBetween(t *testing.T) {
+	at := func(s string) time.Time {
+		t.Helper()
+		ts, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	}
+	entries := []ChangelogEntry{
+		{Version: "1.5.0", ReleasedAt: at("2024-07-01T00:00:00Z")},
+		{Version: "1.4.0", ReleasedAt: at("2024-06-30T23:59:59Z")},
+		{Version: "1.3.0", ReleasedAt: at("2024-06-30T00:00:00Z")},
+		{Version: "1.2.5"},
+		{Version: "1.2.0", ReleasedAt: at("2024-01-01T00:00:00Z")},
+		{Version: "1.1.0", ReleasedAt: at("2023-12-31T23:59:59Z")},
+	}
+	since := parseDateFlag("-since", "2024-01-01")
+	until := parseDateFlag("-until", "2024-06-30")
+
+	tests := []struct {
+		name         string
+		since, until time.Time
+		want         []string
+	}{
+		{"both bounds", since, until, []string{"1.4.0", "1.3.0", "1.2.0"}},
+		{"open upper bound", since, time.Time{}, []string{"1.5.0", "1.4.0", "1.3.0", "1.2.0"}},
+		{"open lower bound", time.Time{}, until, []string{"1.4.0", "1.3.0", "1.2.0", "1.1.0"}},
+		{"both open", time.Time{}, time.Time{}, []string{"1.5.0", "1.4.0", "1.3.0", "1.2.0", "1.1.0"}},
+		{"single day", until, until, []string{"1.4.0", "1.3.0"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var versions []string
+			for _, entry := range entriesBetween(entries, tt.since, tt.until) {
+				versions = append(versions, entry.Version)
+			}
+			if !reflect.DeepEqual(versions, tt.want) {
+				t.Errorf("versions = %v, want %v", versions, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDateFlagRejectsBadDates(t *testing.T) {
+	for _, value := range []string{"2024-13-01", "2024/06/30", "yesterday", "2024-06-31"} {
+		_, stderr, code := runAIC(t, "claude", "-list", "-until", value)
+		want := "-until expects a date (YYYY-MM-DD), got '" + value + "'"
+		if code != 1 || !strings.Contains(stderr, want) {
+			t.Errorf("-until %s: exit %d, stderr %q; want %q", value, code, stderr, want)
+		}
+	}
+}
+
+func TestNewestNMarkdown(t *testing.T) {
+	tests := []struct {
+		name, changelog, want string
+	}{
+		{
+			name:      "sections",
+			changelog: "## [1.2.0] - 2024-02-01\n\n### Added\n\n- A\n\n## [1.1.0] - 2024-01-01\n\n### Fixed\n\n- B\n\n## [1.0.0] - 2023-12-01\n\n- C\n",
+			want:      "## 1.2.0 (2024-02-01)\n\n### Added\n\n- A\n\n## 1.1.0 (2024-01-01)\n\n### Fixed\n\n- B\n",
+		},
+		{
+			name:      "ungrouped",
+			changelog: "## 1.2.0\n\n- A\n\n## 1.1.0\n\n- B\n\n## 1.0.0\n\n- C\n",
+			want:      "## 1.2.0\n\n- A\n\n## 1.1.0\n\n- B\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "CHANGELOG.md")
+			os.WriteFile(path, []byte(tt.changelog), 0o644)
+			stdout, stderr, code := runAIC(t, "file", path, "-n", "2", "-md")
+			if code != 0 {
+				t.Fatalf("exit %d: %s", code, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("output:\n%q\nwant:\n%q", stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrefixUsesSourceName(t *testing.T) {
+	dir := t.TempDir()
+	aa := filepath.Join(dir, "aa.md")
+	bb := filepath.Join(dir, "bb.md")
+	os.WriteFile(aa, []byte("## 1.2.0\n\n- Two\n"), 0o644)
+	os.WriteFile(bb, []byte("## 0.5.0\n\n- Five\n"), 0o644)
+	config := fmt.Sprintf("[sources.aa]\nkind = \"markdown-raw\"\nurls = %q\ndisplay_name = \"Tool A\"\n\n[sources.bb]\nkind = \"markdown-raw\"\nurls = %q\ndisplay_name = \"Tool B\"\n", aa, bb)
+
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"single", []string{"aa", "-prefix", "-no-separator"}, "[aa] Tool A 1.2.0\n[aa]   * Two\n"},
+		{"multi", []string{"aa", "bb", "-prefix", "-no-separator"}, "[aa] Tool A 1.2.0\n[aa]   * Two\n\n[bb] Tool B 0.5.0\n[bb]   * Five\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := runAICWithConfig(t, config, tt.args...)
+			if code != 0 {
+				t.Fatalf("exit %d: %s", code, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("output:\n%q\nwant:\n%q", stdout, tt.want)
+			}
+		})
+	}
+}
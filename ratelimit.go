@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultGitHubRate is how many GitHub API requests per second are sent at
+// most unless the config file says otherwise. Bursts of concurrent requests
+// can trip GitHub's secondary rate limits even when the hourly quota is fine.
+const defaultGitHubRate = 10
+
+// githubLimiter spaces out GitHub API requests across every goroutine.
+var githubLimiter = newRateLimiter(defaultGitHubRate)
+
+// rateLimiter is a token bucket holding a single token, so callers of wait
+// proceed at most rate times a second, evenly spaced. A nil limiter never
+// waits.
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// newRateLimiter returns a limiter allowing perSecond calls a second, or nil
+// when perSecond is 0 or less.
+func newRateLimiter(perSecond int) *rateLimiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{interval: time.Second / time.Duration(perSecond)}
+}
+
+// wait blocks until the caller may proceed. Waiting callers are given
+// consecutive slots, so none of them is starved.
+func (l *rateLimiter) wait() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(slot))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+)
+
+// sectionAliases maps lowercased section names used across sources to the
+// canonical category names applied by -normalize-sections.
+var sectionAliases = map[string]string{
+	"added":        "Added",
+	"new":          "Added",
+	"new features": "Added",
+	"features":     "Added",
+	"feature":      "Added",
+	"feat":         "Added",
+	"what's new":   "Added",
+
+	"changed":      "Changed",
+	"changes":      "Changed",
+	"improvements": "Changed",
+	"improved":     "Changed",
+	"enhancements": "Changed",
+	"updates":      "Changed",
+	"updated":      "Changed",
+	"refactor":     "Changed",
+
+	"deprecated":   "Deprecated",
+	"deprecations": "Deprecated",
+
+	"removed":  "Removed",
+	"removals": "Removed",
+	"dropped":  "Removed",
+
+	"fixed":     "Fixed",
+	"fixes":     "Fixed",
+	"fix":       "Fixed",
+	"bug fixes": "Fixed",
+	"bugfixes":  "Fixed",
+	"bug fix":   "Fixed",
+	"bugs":      "Fixed",
+
+	"security":       "Security",
+	"security fixes": "Security",
+
+	"performance":              "Performance",
+	"performance improvements": "Performance",
+	"perf":                     "Performance",
+
+	"documentation": "Documentation",
+	"docs":          "Documentation",
+
+	"breaking changes": "Breaking Changes",
+	"breaking":         "Breaking Changes",
+}
+
+// normalizeSectionName maps a section name to its canonical category, ignoring
+// case, leading emoji and trailing colons. Names without a known category are
+// returned unchanged.
+func normalizeSectionName(name string) string {
+	key := strings.ToLower(stripLeadingEmoji(name))
+	key = strings.Join(strings.Fields(strings.TrimRight(key, ": ")), " ")
+	if canonical, ok := sectionAliases[key]; ok {
+		return canonical
+	}
+	return name
+}
+
+// normalizeSections renames the sections of entry to canonical categories,
+// keeping the source's name in OriginalName.
+func normalizeSections(entry ChangelogEntry) ChangelogEntry {
+	sections := make([]Section, len(entry.Sections))
+	for i, section := range entry.Sections {
+		if canonical := normalizeSectionName(section.Name); canonical != section.Name {
+			section.OriginalName = section.Name
+			section.Name = canonical
+		}
+		sections[i] = section
+	}
+	entry.Sections = sections
+	return entry
+}
+
+// sortChanges returns a copy of entry with the changes of each section, and
+// the ungrouped changes, sorted case-insensitively.
+func sortChanges(entry ChangelogEntry) ChangelogEntry {
+	sections := make([]Section, len(entry.Sections))
+	for i, section := range entry.Sections {
+		section.Changes = sortedFold(section.Changes)
+		sections[i] = section
+	}
+	entry.Sections = sections
+	entry.Changes = sortedFold(entry.Changes)
+	return entry
+}
+
+func sortedFold(changes []string) []string {
+	if changes == nil {
+		return nil
+	}
+	sorted := slices.Clone(changes)
+	slices.SortStableFunc(sorted, func(a, b string) int {
+		return cmp.Or(cmp.Compare(strings.ToLower(a), strings.ToLower(b)), cmp.Compare(a, b))
+	})
+	return sorted
+}
+
+// flattenSingleSection returns a copy of entry in which the changes of a lone
+// section become ungrouped changes, dropping a header that only wraps the
+// whole release. Entries with several sections are returned unchanged.
+func flattenSingleSection(entry ChangelogEntry) ChangelogEntry {
+	if len(entry.Sections) != 1 {
+		return entry
+	}
+	entry.Changes = append(slices.Clone(entry.Sections[0].Changes), entry.Changes...)
+	entry.Sections = nil
+	return entry
+}
+
+// flattenSections returns a copy of entry with every change ungrouped: the
+// changes of each section in order, then the ungrouped changes.
+func flattenSections(entry ChangelogEntry) ChangelogEntry {
+	var changes []string
+	for _, section := range entry.Sections {
+		changes = append(changes, section.Changes...)
+	}
+	entry.Changes = append(changes, entry.Changes...)
+	entry.Sections = nil
+	return entry
+}
+
+// foldUngrouped returns a copy of entry with its ungrouped changes moved to
+// the end of a section named "Other", so that every change is in a section.
+func foldUngrouped(entry ChangelogEntry) ChangelogEntry {
+	if len(entry.Changes) == 0 {
+		return entry
+	}
+	sections := slices.Clone(entry.Sections)
+	i := slices.IndexFunc(sections, func(s Section) bool { return s.Name == "Other" })
+	if i < 0 {
+		sections = append(sections, Section{Name: "Other"})
+		i = len(sections) - 1
+	}
+	sections[i].Changes = append(slices.Clone(sections[i].Changes), entry.Changes...)
+	entry.Sections = sections
+	entry.Changes = nil
+	return entry
+}
+
+// stripEntryMarkdown returns a copy of entry with markdown removed from every
+// change.
+func stripEntryMarkdown(entry ChangelogEntry) ChangelogEntry {
+	sections := make([]Section, len(entry.Sections))
+	for i, section := range entry.Sections {
+		section.Changes = stripAll(section.Changes)
+		sections[i] = section
+	}
+	entry.Sections = sections
+	entry.Changes = stripAll(entry.Changes)
+	return entry
+}
+
+// annotateVersion returns a copy of entry with each change prefixed by the
+// entry's version in brackets, such as "[1.2.3] ", so changes stay traceable
+// once several entries are listed together.
+func annotateVersion(entry ChangelogEntry) ChangelogEntry {
+	prefix := "[" + entry.Version + "] "
+	sections := make([]Section, len(entry.Sections))
+	for i, section := range entry.Sections {
+		section.Changes = prefixAll(prefix, section.Changes)
+		sections[i] = section
+	}
+	entry.Sections = sections
+	entry.Changes = prefixAll(prefix, entry.Changes)
+	return entry
+}
+
+func prefixAll(prefix string, changes []string) []string {
+	if changes == nil {
+		return nil
+	}
+	prefixed := make([]string, len(changes))
+	for i, change := range changes {
+		prefixed[i] = prefix + change
+	}
+	return prefixed
+}
+
+func stripAll(changes []string) []string {
+	if changes == nil {
+		return nil
+	}
+	stripped := make([]string, len(changes))
+	for i, change := range changes {
+		stripped[i] = stripMarkdown(change)
+	}
+	return stripped
+}
+
+// mergeSections returns a copy of entry in which sections with the same name,
+// ignoring case and leading emoji, are combined into the first of them, with
+// their changes in order.
+func mergeSections(entry ChangelogEntry) ChangelogEntry {
+	var sections []Section
+	index := map[string]int{}
+	for _, section := range entry.Sections {
+		key := strings.ToLower(strings.TrimSpace(stripLeadingEmoji(section.Name)))
+		if i, ok := index[key]; ok {
+			sections[i].Changes = append(slices.Clip(sections[i].Changes), section.Changes...)
+			continue
+		}
+		index[key] = len(sections)
+		sections = append(sections, section)
+	}
+	entry.Sections = sections
+	return entry
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeSectionName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Added", "Added"},
+		{"New Features", "Added"},
+		{"✨ Features", "Added"},
+		{"Bug Fixes", "Fixed"},
+		{"🐛 Bug Fixes", "Fixed"},
+		{"Fixes:", "Fixed"},
+		{"BUGFIXES", "Fixed"},
+		{"Improvements", "Changed"},
+		{"Removals", "Removed"},
+		{"Security", "Security"},
+		{"Performance  Improvements", "Performance"},
+		{"Breaking", "Breaking Changes"},
+		{"Docs", "Documentation"},
+		{"Miscellaneous", "Miscellaneous"},
+		{"🚀 Launch notes", "🚀 Launch notes"},
+	}
+	for _, tt := range tests {
+		if got := normalizeSectionName(tt.name); got != tt.want {
+			t.Errorf("normalizeSectionName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeSectionsKeepsOriginalName(t *testing.T) {
+	entry := ChangelogEntry{Sections: []Section{
+		{Name: "🐛 Bug Fixes", Changes: []string{"Crash"}},
+		{Name: "Added", Changes: []string{"Flag"}},
+	}}
+	got := normalizeSections(entry)
+
+	data, err := json.Marshal(got.Sections)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"name":"Fixed","changes":["Crash"],"original_name":"🐛 Bug Fixes"},{"name":"Added","changes":["Flag"]}]`
+	if string(data) != want {
+		t.Errorf("sections JSON = %s, want %s", data, want)
+	}
+	if entry.Sections[0].Name != "🐛 Bug Fixes" {
+		t.Errorf("normalizeSections changed its argument's section to %q", entry.Sections[0].Name)
+	}
+}
+
+func TestFlattenSingleSection(t *testing.T) {
+	tests := []struct {
+		name, body   string
+		wantSections []string
+		wantChanges  []string
+	}{
+		{
+			name:        "single section",
+			body:        "## What's New\n\n- One\n- Two",
+			wantChanges: []string{"One", "Two"},
+		},
+		{
+			name:         "several sections",
+			body:         "### Added\n\n- One\n\n### Fixed\n\n- Two",
+			wantSections: []string{"Added", "Fixed"},
+		},
+		{
+			name:        "single section and ungrouped",
+			body:        "- Loose\n\n### Changes\n\n- One",
+			wantChanges: []string{"One", "Loose"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections, changes := parseReleaseBody(tt.body)
+			got := flattenSingleSection(ChangelogEntry{Sections: sections, Changes: changes})
+			var names []string
+			for _, section := range got.Sections {
+				names = append(names, section.Name)
+			}
+			if !reflect.DeepEqual(names, tt.wantSections) {
+				t.Errorf("sections = %v, want %v", names, tt.wantSections)
+			}
+			if !reflect.DeepEqual(got.Changes, tt.wantChanges) {
+				t.Errorf("changes = %v, want %v", got.Changes, tt.wantChanges)
+			}
+		})
+	}
+}
+
+func TestMergeSections(t *testing.T) {
+	const body = "### Bug Fixes\n\n- One\n\n### Added\n\n- Flag\n\n### 🐛 bug fixes\n\n- Two"
+	tests := []struct {
+		name string
+		opts outputOptions
+		want []Section
+	}{
+		{
+			name: "merged by default",
+			want: []Section{
+				{Name: "Bug Fixes", Changes: []string{"One", "Two"}},
+				{Name: "Added", Changes: []string{"Flag"}},
+			},
+		},
+		{
+			name: "-no-merge-sections",
+			opts: outputOptions{noMergeSections: true},
+			want: []Section{
+				{Name: "Bug Fixes", Changes: []string{"One"}},
+				{Name: "Added", Changes: []string{"Flag"}},
+				{Name: "🐛 bug fixes", Changes: []string{"Two"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sections, _ := parseReleaseBody(body)
+			entry := ChangelogEntry{Sections: sections}
+			got := applyOutputOptions(entry, tt.opts)
+			if !reflect.DeepEqual(got.Sections, tt.want) {
+				t.Errorf("sections = %q, want %q", got.Sections, tt.want)
+			}
+			if len(entry.Sections[0].Changes) != 1 {
+				t.Errorf("merging changed the parsed section to %q", entry.Sections[0].Changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// runServe implements "aic serve", an HTTP server exposing the changelogs as
+// JSON, and returns the exit code.
+func runServe(args []string, cfg *Config) int {
+	addr := ":8080"
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-addr", "--addr":
+			if i+1 < len(args) {
+				addr = args[i+1]
+				i++
+			}
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", serveHealth)
+	mux.HandleFunc("GET /latest", serveLatest)
+	mux.HandleFunc("GET /{source}", func(w http.ResponseWriter, r *http.Request) {
+		serveEntry(w, cfg.resolveAlias(r.PathValue("source")), "")
+	})
+	mux.HandleFunc("GET /{source}/{version}", func(w http.ResponseWriter, r *http.Request) {
+		serveEntry(w, cfg.resolveAlias(r.PathValue("source")), r.PathValue("version"))
+	})
+
+	if !quiet {
+		fmt.Fprintf(os.Stderr, "Listening on %s\n", addr)
+	}
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, format string, a ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, a...)})
+}
+
+// serveHealth reports whether the server can do its job: that the cache
+// directory is usable and that the GitHub API, which most sources are
+// fetched from, answers and has requests left. Other hosts aren't checked.
+func serveHealth(w http.ResponseWriter, r *http.Request) {
+	checks := map[string]string{"cache": "ok"}
+	status := http.StatusOK
+
+	if msg, ok := checkGitHub(); !ok {
+		checks["github"] = msg
+		status = http.StatusServiceUnavailable
+	} else {
+		checks["github"] = "ok"
+	}
+
+	if noCache {
+		checks["cache"] = "disabled"
+	} else if dir, err := cacheDir(); err != nil {
+		checks["cache"] = err.Error()
+		status = http.StatusServiceUnavailable
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		checks["cache"] = err.Error()
+		status = http.StatusServiceUnavailable
+	}
+
+	result := "ok"
+	if status != http.StatusOK {
+		result = "fail"
+	}
+	writeJSON(w, status, map[string]any{"status": result, "checks": checks})
+}
+
+// checkGitHub asks the GitHub API for its rate limit, which doesn't count
+// against it, and returns a problem description and false when the API can't
+// be reached or no requests are left.
+func checkGitHub() (string, bool) {
+	req, err := newRequest(githubAPIURL("/rate_limit"))
+	if err != nil {
+		return err.Error(), false
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err.Error(), false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Sprintf("HTTP %d: %s", resp.StatusCode, resp.Status), false
+	}
+
+	var limits struct {
+		Resources struct {
+			Core struct {
+				Remaining int   `json:"remaining"`
+				Reset     int64 `json:"reset"`
+			} `json:"core"`
+		} `json:"resources"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&limits); err != nil {
+		return fmt.Sprintf("reading rate limit: %v", err), false
+	}
+	if core := limits.Resources.Core; core.Remaining == 0 {
+		return "rate limited until " + time.Unix(core.Reset, 0).UTC().Format(time.RFC3339), false
+	}
+	return "", true
+}
+
+// serveLatest returns the releases of every source from the last 24 hours,
+// with the default output options applied as for "aic latest -json".
+func serveLatest(w http.ResponseWriter, r *http.Request) {
+	entries := fetchLatest(sources, time.Now().Add(-24*time.Hour), func(display string, err error) {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch %s: %v\n", display, err)
+		}
+	})
+	shown := []ChangelogEntry{}
+	for _, entry := range entries {
+		shown = append(shown, applyOutputOptions(entry, outputOptions{}))
+	}
+	writeJSON(w, http.StatusOK, shown)
+}
+
+// serveEntry returns one entry of a source: the given version, or the newest
+// when version is empty. Like the CLI's -json output, it has the default
+// output options applied.
+func serveEntry(w http.ResponseWriter, name, version string) {
+	src, ok := sources[name]
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "unknown source '%s'", name)
+		return
+	}
+
+	entries, err := fetchSource(src)
+	if err != nil {
+		writeJSONError(w, http.StatusBadGateway, "failed to fetch changelog: %v", err)
+		return
+	}
+	if len(entries) == 0 {
+		writeJSONError(w, http.StatusNotFound, "no changelog entries found")
+		return
+	}
+
+	entry := selectEntry(entries, version)
+	if entry == nil {
+		writeJSONError(w, http.StatusNotFound, "version %s not found", version)
+		return
+	}
+	writeJSON(w, http.StatusOK, applyOutputOptions(*entry, outputOptions{}))
+}
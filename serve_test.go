@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServeEntryAppliesOutputOptions(t *testing.T) {
+	sources["tool"] = testSource("tool", ChangelogEntry{Version: "1.0.0", Sections: []Section{
+		{Name: "Bug Fixes", Changes: []string{"One"}},
+		{Name: "bug fixes", Changes: []string{"Two"}},
+	}})
+	t.Cleanup(func() { delete(sources, "tool") })
+
+	rec := httptest.NewRecorder()
+	serveEntry(rec, "tool", "")
+	var got ChangelogEntry
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []Section{{Name: "Bug Fixes", Changes: []string{"One", "Two"}}}
+	if !reflect.DeepEqual(got.Sections, want) {
+		t.Errorf("sections = %+v, want them merged as by aic tool -json", got.Sections)
+	}
+}
+
+func TestServeHealth(t *testing.T) {
+	tests := []struct {
+		name, body string
+		status     int
+		github     string
+	}{
+		{"ok", `{"resources": {"core": {"remaining": 42, "reset": 1717200000}}}`, http.StatusOK, "ok"},
+		{"rate limited", `{"resources": {"core": {"remaining": 0, "reset": 1717200000}}}`, http.StatusServiceUnavailable, "rate limited until 2024-06-01T00:00:00Z"},
+		{"api down", "", http.StatusServiceUnavailable, "HTTP 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newTestGitHub(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.body == "" || r.URL.Path != "/api/v3/rate_limit" {
+					http.Error(w, "down", http.StatusInternalServerError)
+					return
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+
+			rec := httptest.NewRecorder()
+			serveHealth(rec, httptest.NewRequest("GET", "/healthz", nil))
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			var got struct {
+				Checks map[string]string `json:"checks"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(got.Checks["github"], tt.github) {
+				t.Errorf("github check = %q, want %q", got.Checks["github"], tt.github)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// statePath returns the file recording the newest version seen per source.
+// AIC_STATE overrides the default of $XDG_STATE_HOME/aic/state.json, or
+// ~/.local/state/aic/state.json when XDG_STATE_HOME is unset.
+func statePath() (string, error) {
+	if p := os.Getenv("AIC_STATE"); p != "" {
+		return p, nil
+	}
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "aic", "state.json"), nil
+}
+
+// seenState maps a source key to the newest version seen for it.
+type seenState map[string]string
+
+func loadState() (seenState, error) {
+	state := seenState{}
+	path, err := statePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (s seenState) save() error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, append(data, '\n'), 0o644)
+}
+
+// stateKey identifies src in the state file. File and gist sources include
+// their location, since their names are shared.
+func stateKey(src Source) string {
+	if (src.Name == "file" || src.Name == "gist") && src.RequestURLs != nil {
+		if urls := src.RequestURLs(); len(urls) > 0 {
+			return src.Name + ":" + urls[0]
+		}
+	}
+	return src.Name
+}
+
+// markSeen records version as the newest seen for src and reports whether it
+// differs from the previously recorded one.
+func markSeen(src Source, version string) (bool, error) {
+	state, err := loadState()
+	if err != nil {
+		return false, err
+	}
+	key := stateKey(src)
+	if state[key] == version {
+		return false, nil
+	}
+	state[key] = version
+	return true, state.save()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are the functions available to -template and -template-file
+// besides text/template's built-ins.
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	// date formats a release date as YYYY-MM-DD, or "" when it is unknown.
+	"date": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format("2006-01-02")
+	},
+	// changes lists every change of an entry, sections first.
+	"changes": func(entry ChangelogEntry) []string {
+		return flattenSections(entry).Changes
+	},
+}
+
+// parseOutputTemplate parses text as a Go template for entries, named after
+// where it came from so errors point at it.
+func parseOutputTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// loadTemplateFile reads and parses the template in the file at path.
+func loadTemplateFile(path string) (*template.Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseOutputTemplate(path, string(data))
+}
+
+// outputTemplate executes tmpl with entry, whose Source is set to
+// displayName. A missing trailing newline is added.
+func outputTemplate(w io.Writer, tmpl *template.Template, displayName string, entry ChangelogEntry) error {
+	entry.Source = displayName
+	var out strings.Builder
+	if err := tmpl.Execute(&out, entry); err != nil {
+		return err
+	}
+	text := out.String()
+	if text != "" && !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	_, err := fmt.Fprint(w, text)
+	return err
+}
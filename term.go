@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalWidth returns the width of the terminal on stdout, preferring the
+// COLUMNS environment variable. It returns 0 when the width is unknown.
+func terminalWidth() int {
+	if cols, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && cols > 0 {
+		return cols
+	}
+	if !isTerminal(os.Stdout) {
+		return 0
+	}
+	return ttyWidth(os.Stdout)
+}
+
+// progress reports on stderr how many of a batch of fetches have finished.
+// It is silent when stderr isn't a terminal or -quiet is set.
+type progress struct {
+	mu      sync.Mutex
+	enabled bool
+	label   string
+	done    int
+	total   int
+}
+
+func newProgress(total int) *progress {
+	return &progress{enabled: !quiet && isTerminal(os.Stderr), total: total}
+}
+
+func (p *progress) start(label string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.label = label
+	p.render("")
+}
+
+// step records that the fetch named name has finished.
+func (p *progress) step(name string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done++
+	p.render(name + " done")
+}
+
+// clear erases the progress line so other output starts on a clean line.
+func (p *progress) clear() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.enabled {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
+
+func (p *progress) render(status string) {
+	if !p.enabled {
+		return
+	}
+	line := fmt.Sprintf("%s… %d/%d", p.label, p.done, p.total)
+	if status != "" {
+		line += " (" + status + ")"
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K%s", line)
+}
+
+// pageProgress reports on stderr the pages fetched so far of a paginated
+// fetch. Like progress, it is silent when stderr isn't a terminal or -quiet
+// is set.
+type pageProgress struct {
+	enabled bool
+	shown   bool
+}
+
+func newPageProgress(enabled bool) *pageProgress {
+	return &pageProgress{enabled: enabled && !quiet && isTerminal(os.Stderr)}
+}
+
+// page records that page n of total has been fetched; total is 0 when
+// unknown.
+func (p *pageProgress) page(n, total int) {
+	if !p.enabled {
+		return
+	}
+	p.shown = true
+	if total > 0 {
+		fmt.Fprintf(os.Stderr, "\r\033[Kfetched page %d/%d", n, total)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[Kfetched page %d", n)
+}
+
+// clear erases the progress line, if one was drawn.
+func (p *pageProgress) clear() {
+	if p.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+	}
+}
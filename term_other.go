@@ -0,0 +1,23 @@
+//go:build !linux && !darwin
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// ttyWidth is not implemented on this platform; COLUMNS is used instead.
+func ttyWidth(f *os.File) int {
+	return 0
+}
+
+// ttySize is not implemented on this platform.
+func ttySize(f *os.File) (cols, rows int) {
+	return 0, 0
+}
+
+// makeRaw is not implemented on this platform.
+func makeRaw(f *os.File) (func(), error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
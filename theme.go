@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// palette holds the SGR codes used to color parts of plain text output. An
+// empty code leaves that part uncolored, so the zero palette disables color.
+type palette struct {
+	header  string
+	date    string
+	section string
+	bullet  string
+}
+
+var themes = map[string]palette{
+	"dark":  {header: "1;96", date: "90", section: "1;33", bullet: "32"},
+	"light": {header: "1;34", date: "90", section: "1;35", bullet: "36"},
+	"mono":  {header: "1", section: "1"},
+}
+
+// parseThemeFlag returns the palette named by the -theme value, exiting on an
+// unknown name.
+func parseThemeFlag(value string) palette {
+	p, ok := themes[value]
+	if !ok {
+		var names []string
+		for name := range themes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fatalf("invalid -theme '%s' (expected %s)", value, strings.Join(names, ", "))
+	}
+	return p
+}
+
+// colorEnabled reports whether plain text output should be colored: stdout
+// must be a terminal and NO_COLOR unset.
+func colorEnabled() bool {
+	return os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
+
+// paint wraps s in the SGR code, unless the code is empty.
+func (p palette) paint(code, s string) string {
+	if code == "" {
+		return s
+	}
+	return fmt.Sprintf("\033[%sm%s\033[0m", code, s)
+}
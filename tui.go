@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Panes of the TUI browser, left to right.
+const (
+	paneSources = iota
+	paneVersions
+	paneChanges
+)
+
+// tuiState is the state of the "aic tui" browser. Entries are fetched the
+// first time a source's versions are opened.
+type tuiState struct {
+	sources []Source
+	entries map[string][]ChangelogEntry
+	errs    map[string]error
+
+	pane      int
+	source    int
+	version   int
+	scroll    int
+	query     string
+	searching bool
+	input     string
+	loading   bool
+}
+
+// runTUI runs the interactive browser and returns the exit code.
+func runTUI() int {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		fmt.Fprintf(os.Stderr, "Error: tui requires a terminal\n")
+		return 1
+	}
+
+	restore, err := makeRaw(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer restore()
+
+	fmt.Print("\033[?1049h\033[?25l")
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	state := newTUIState()
+	buf := make([]byte, 16)
+	for {
+		cols, rows := ttySize(os.Stdout)
+		state.render(os.Stdout, cols, rows)
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return 0
+		}
+		if !state.handleKey(string(buf[:n])) {
+			return 0
+		}
+
+		if state.loading {
+			state.render(os.Stdout, cols, rows)
+			state.load()
+		}
+	}
+}
+
+func newTUIState() *tuiState {
+	state := &tuiState{
+		entries: map[string][]ChangelogEntry{},
+		errs:    map[string]error{},
+	}
+	for _, src := range sources {
+		state.sources = append(state.sources, src)
+	}
+	sort.Slice(state.sources, func(i, j int) bool {
+		return state.sources[i].Name < state.sources[j].Name
+	})
+	return state
+}
+
+// load fetches the selected source's entries.
+func (s *tuiState) load() {
+	src := s.sources[s.source]
+	entries, err := fetchSource(src)
+	if err != nil {
+		s.errs[src.Name] = err
+	} else {
+		s.entries[src.Name] = entries
+	}
+	s.loading = false
+}
+
+// visibleEntries returns the selected source's entries matching the search
+// query.
+func (s *tuiState) visibleEntries() []ChangelogEntry {
+	entries := s.entries[s.sources[s.source].Name]
+	if s.query == "" {
+		return entries
+	}
+
+	query := strings.ToLower(s.query)
+	var matches []ChangelogEntry
+	for _, entry := range entries {
+		if entryContains(entry, query) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// entryContains reports whether the version, a section name or a change of
+// entry contains the lowercase query.
+func entryContains(entry ChangelogEntry, query string) bool {
+	texts := []string{entry.Version}
+	texts = append(texts, entry.Changes...)
+	for _, section := range entry.Sections {
+		texts = append(texts, section.Name)
+		texts = append(texts, section.Changes...)
+	}
+	for _, text := range texts {
+		if strings.Contains(strings.ToLower(text), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleKey updates the state for a key press and reports whether the
+// browser should keep running.
+func (s *tuiState) handleKey(key string) bool {
+	if s.searching {
+		switch key {
+		case "\r", "\n":
+			s.query = s.input
+			s.searching = false
+			s.version, s.scroll = 0, 0
+		case "\x1b":
+			s.searching = false
+		case "\x7f", "\b":
+			if s.input != "" {
+				_, size := utf8.DecodeLastRuneInString(s.input)
+				s.input = s.input[:len(s.input)-size]
+			}
+		case "\x03":
+			return false
+		default:
+			if utf8.ValidString(key) && !strings.ContainsAny(key, "\x1b\r\n") {
+				s.input += key
+			}
+		}
+		return true
+	}
+
+	switch key {
+	case "q", "\x03":
+		return false
+	case "/":
+		s.searching = true
+		s.input = s.query
+	case "\x1b":
+		s.query = ""
+	case "\x1b[A", "\x1bOA", "k":
+		s.move(-1)
+	case "\x1b[B", "\x1bOB", "j":
+		s.move(1)
+	case "\x1b[D", "\x1bOD", "h":
+		if s.pane > paneSources {
+			s.pane--
+		}
+	case "\x1b[C", "\x1bOC", "l", "\r", "\n", "\t":
+		if s.pane < paneChanges {
+			s.pane++
+		}
+		name := s.sources[s.source].Name
+		if s.pane == paneVersions && s.entries[name] == nil && s.errs[name] == nil {
+			s.loading = true
+		}
+	}
+	return true
+}
+
+// move moves the cursor of the focused pane by delta.
+func (s *tuiState) move(delta int) {
+	switch s.pane {
+	case paneSources:
+		s.source = clamp(s.source+delta, 0, len(s.sources)-1)
+		s.version, s.scroll, s.query = 0, 0, ""
+	case paneVersions:
+		s.version = clamp(s.version+delta, 0, len(s.visibleEntries())-1)
+		s.scroll = 0
+	case paneChanges:
+		s.scroll = max(s.scroll+delta, 0)
+	}
+}
+
+func clamp(n, lo, hi int) int {
+	if n > hi {
+		n = hi
+	}
+	if n < lo {
+		n = lo
+	}
+	return n
+}
+
+// render draws the three panes and a status line on w, sized to cols by
+// rows.
+func (s *tuiState) render(w io.Writer, cols, rows int) {
+	if cols <= 0 {
+		cols = 80
+	}
+	if rows <= 0 {
+		rows = 24
+	}
+
+	srcWidth, verWidth := 18, 20
+	changesWidth := max(cols-srcWidth-verWidth-6, 10)
+	height := max(rows-2, 1)
+
+	var srcLines, verLines []string
+	for _, src := range s.sources {
+		srcLines = append(srcLines, src.Name)
+	}
+
+	entries := s.visibleEntries()
+	name := s.sources[s.source].Name
+	switch {
+	case s.loading:
+		verLines = []string{"Loading…"}
+	case s.errs[name] != nil:
+		verLines = []string{"Fetch failed"}
+	case s.entries[name] == nil:
+		verLines = []string{"→ to load"}
+	case len(entries) == 0:
+		verLines = []string{"No matches"}
+	}
+	if verLines == nil {
+		for _, entry := range entries {
+			verLines = append(verLines, entry.Version)
+		}
+	}
+
+	var changeLines []string
+	if err := s.errs[name]; err != nil {
+		changeLines = wrapLines(err.Error(), changesWidth, "")
+	} else if s.version < len(entries) {
+		changeLines = entryLines(&entries[s.version], changesWidth)
+	}
+	s.scroll = min(s.scroll, max(len(changeLines)-height, 0))
+	changeLines = changeLines[s.scroll:]
+
+	srcCursor, verCursor := s.source, -1
+	if len(entries) > 0 && !s.loading {
+		verCursor = s.version
+	}
+	srcOffset := max(srcCursor-height+1, 0)
+	verOffset := max(verCursor-height+1, 0)
+
+	var b strings.Builder
+	b.WriteString("\033[H\033[2J")
+	b.WriteString(tuiHeader("Sources", srcWidth, s.pane == paneSources))
+	b.WriteString(" │ ")
+	b.WriteString(tuiHeader("Versions", verWidth, s.pane == paneVersions))
+	b.WriteString(" │ ")
+	b.WriteString(tuiHeader("Changes", changesWidth, s.pane == paneChanges))
+	b.WriteString("\r\n")
+
+	for row := 0; row < height; row++ {
+		b.WriteString(tuiCell(srcLines, srcOffset+row, srcWidth, srcOffset+row == srcCursor, s.pane == paneSources))
+		b.WriteString(" │ ")
+		b.WriteString(tuiCell(verLines, verOffset+row, verWidth, verOffset+row == verCursor, s.pane == paneVersions))
+		b.WriteString(" │ ")
+		b.WriteString(tuiCell(changeLines, row, changesWidth, false, false))
+		b.WriteString("\r\n")
+	}
+
+	switch {
+	case s.searching:
+		fmt.Fprintf(&b, "/%s", s.input)
+	case s.query != "":
+		fmt.Fprintf(&b, "Filter: %s  (esc clears, / edits, q quits)", s.query)
+	default:
+		b.WriteString("↑↓ move  ←→ panes  / search  q quit")
+	}
+
+	io.WriteString(w, b.String())
+}
+
+// entryLines lays out entry for the changes pane, wrapped to width.
+func entryLines(entry *ChangelogEntry, width int) []string {
+	title := entry.Version
+	if !entry.ReleasedAt.IsZero() {
+		title += " (" + entry.ReleasedAt.Format("2006-01-02") + ")"
+	}
+	lines := []string{title}
+
+	for _, section := range entry.Sections {
+		lines = append(lines, "", "["+section.Name+"]")
+		for _, change := range section.Changes {
+			lines = append(lines, wrapLines(change, width, "  * ")...)
+		}
+	}
+	if len(entry.Changes) > 0 {
+		lines = append(lines, "")
+	}
+	for _, change := range entry.Changes {
+		lines = append(lines, wrapLines(change, width, "  * ")...)
+	}
+	return lines
+}
+
+// wrapLines wraps text after prefix to width columns and splits it into
+// lines.
+func wrapLines(text string, width int, prefix string) []string {
+	return strings.Split(prefix+wrapText(text, width, utf8.RuneCountInString(prefix)), "\n")
+}
+
+func tuiHeader(title string, width int, focused bool) string {
+	if focused {
+		return "\033[1m" + padText(title, width) + "\033[0m"
+	}
+	return "\033[2m" + padText(title, width) + "\033[0m"
+}
+
+// tuiCell renders line i of lines padded to width, highlighted when it is the
+// cursor of the focused pane and bold when it is the cursor of another.
+func tuiCell(lines []string, i, width int, cursor, focused bool) string {
+	text := ""
+	if i >= 0 && i < len(lines) {
+		text = lines[i]
+	}
+	cell := padText(text, width)
+	switch {
+	case cursor && focused:
+		return "\033[7m" + cell + "\033[0m"
+	case cursor:
+		return "\033[1m" + cell + "\033[0m"
+	}
+	return cell
+}
+
+// padText truncates or pads text to exactly width runes.
+func padText(text string, width int) string {
+	text = truncateText(text, width)
+	return text + strings.Repeat(" ", width-utf8.RuneCountInString(text))
+}
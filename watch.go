@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// watchEvent is one release found by "aic watch", as written by -json-stream.
+// Source is the source name, as given on the command line.
+type watchEvent struct {
+	Source      string    `json:"source"`
+	DisplayName string    `json:"display_name"`
+	Version     string    `json:"version"`
+	ReleasedAt  time.Time `json:"released_at,omitzero"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// runWatchCommand implements "aic watch": it polls the sources every
+// interval and reports releases that appear after it started, until it is
+// interrupted. It returns the exit code.
+func runWatchCommand(args []string, cfg *Config) int {
+	interval := 10 * time.Minute
+	var jsonStream bool
+	var include, exclude []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-json-stream", "--json-stream":
+			jsonStream = true
+		case "-interval", "--interval":
+			if i+1 < len(args) {
+				d, err := time.ParseDuration(args[i+1])
+				if err != nil || d < time.Minute {
+					fatalf("-interval expects a duration of at least 1m, got '%s'", args[i+1])
+				}
+				interval = d
+				i++
+			}
+		case "-sources", "--sources":
+			if i+1 < len(args) {
+				include = splitList(args[i+1])
+				i++
+			}
+		case "-exclude-sources", "--exclude-sources":
+			if i+1 < len(args) {
+				exclude = splitList(args[i+1])
+				i++
+			}
+		}
+	}
+
+	w := &watcher{srcs: sortedSources(cfg, include, exclude)}
+	w.seen = make([]map[string]bool, len(w.srcs))
+	encoder := json.NewEncoder(os.Stdout)
+
+	for first := true; ; first = false {
+		for _, event := range w.poll(time.Now()) {
+			if jsonStream {
+				encoder.Encode(event)
+			} else {
+				fmt.Printf("%s  %s %s\n", event.DetectedAt.Format("2006-01-02 15:04"), event.DisplayName, event.Version)
+			}
+		}
+
+		if first && !quiet {
+			fmt.Fprintf(os.Stderr, "Watching %d sources every %s\n", len(w.srcs), interval)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// watcher remembers the versions seen of each source between polls.
+type watcher struct {
+	srcs []Source
+	seen []map[string]bool
+}
+
+// poll fetches every source and returns its releases not seen before, oldest
+// first. A source's first successful fetch only records what it has.
+func (w *watcher) poll(now time.Time) []watchEvent {
+	var events []watchEvent
+	results := fetchEach(w.srcs)
+	for i, src := range w.srcs {
+		r := results[i]
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: Failed to fetch %s: %v\n", src.DisplayName, r.err)
+			continue
+		}
+		if w.seen[i] == nil {
+			w.seen[i] = map[string]bool{}
+			for _, entry := range r.entries {
+				w.seen[i][entry.Version] = true
+			}
+			continue
+		}
+
+		// Entries are newest first
+		for j := len(r.entries) - 1; j >= 0; j-- {
+			entry := r.entries[j]
+			if w.seen[i][entry.Version] {
+				continue
+			}
+			w.seen[i][entry.Version] = true
+			events = append(events, watchEvent{
+				Source:      src.Name,
+				DisplayName: src.DisplayName,
+				Version:     entry.Version,
+				ReleasedAt:  entry.ReleasedAt,
+				DetectedAt:  now,
+			})
+		}
+	}
+	return events
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWatchEventCarriesSourceName(t *testing.T) {
+	entries := []ChangelogEntry{{Version: "1.0.0"}}
+	src := Source{
+		Name:        "claude",
+		DisplayName: "Claude Code",
+		FetchFunc:   func() ([]ChangelogEntry, error) { return entries, nil },
+	}
+	w := &watcher{srcs: []Source{src}, seen: make([]map[string]bool, 1)}
+
+	now := time.Date(2024, 6, 5, 12, 0, 0, 0, time.UTC)
+	if events := w.poll(now); len(events) != 0 {
+		t.Fatalf("first poll: got %d events, want none", len(events))
+	}
+	entries = append([]ChangelogEntry{{Version: "1.1.0"}}, entries...)
+	events := w.poll(now)
+	if len(events) != 1 {
+		t.Fatalf("second poll: got %d events, want 1", len(events))
+	}
+
+	got, err := json.Marshal(events[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"source":"claude","display_name":"Claude Code","version":"1.1.0","detected_at":"2024-06-05T12:00:00Z"}`
+	if string(got) != want {
+		t.Errorf("event:\n%s\nwant:\n%s", got, want)
+	}
+}